@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// passwordAttemptsKey 修改密码时原密码校验次数键
+func passwordAttemptsKey(userID int) string {
+	return fmt.Sprintf("password_attempts:%d", userID)
+}
+
+// passwordLockKey 修改密码锁定键，锁定期间该用户不能修改密码
+func passwordLockKey(userID int) string {
+	return fmt.Sprintf("password_lock:%d", userID)
+}
+
+// passwordLockoutsKey 修改密码锁定次数键，用于计算递增的锁定时长
+func passwordLockoutsKey(userID int) string {
+	return fmt.Sprintf("password_lockouts:%d", userID)
+}
+
+// TakePasswordAttempt 增加原密码校验次数，返回包含本次在内的校验次数，计数在window内有效
+// 先计数再比较密码，并发请求时也不会超过允许的次数
+func TakePasswordAttempt(ctx context.Context, userID int, window time.Duration) (int64, error) {
+	return IncrementRateLimit(ctx, passwordAttemptsKey(userID), window)
+}
+
+// ResetPasswordAttempts 清除原密码校验次数
+func ResetPasswordAttempts(ctx context.Context, userID int) error {
+	return RedisClient.Del(ctx, passwordAttemptsKey(userID)).Err()
+}
+
+// LockPassword 锁定用户修改密码，清除校验次数并增加锁定次数，返回增加后的锁定次数
+// lockDuration 根据锁定次数计算本次锁定时长，锁定次数在window内有效
+func LockPassword(ctx context.Context, userID int, window time.Duration, lockDuration func(lockouts int64) time.Duration) (int64, error) {
+	lockouts, err := IncrementRateLimit(ctx, passwordLockoutsKey(userID), window)
+	if err != nil {
+		return 0, err
+	}
+
+	pipe := RedisClient.TxPipeline()
+	pipe.Set(ctx, passwordLockKey(userID), lockouts, lockDuration(lockouts))
+	pipe.Del(ctx, passwordAttemptsKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return lockouts, nil
+}
+
+// GetPasswordLock 获取用户修改密码的剩余锁定时间，未锁定时返回0
+func GetPasswordLock(ctx context.Context, userID int) (time.Duration, error) {
+	ttl, err := RedisClient.PTTL(ctx, passwordLockKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// 键不存在时返回负数
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-redis/redis/extra/redisotel/v8"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -21,6 +22,7 @@ func InitRedis(cfg *config.RedisConfig) error {
 		DB:       cfg.DB,
 		PoolSize: 10, // 连接池大小
 	})
+	RedisClient.AddHook(redisotel.NewTracingHook())
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -35,49 +37,50 @@ func InitRedis(cfg *config.RedisConfig) error {
 }
 
 // CloseRedis 关闭Redis连接
-func CloseRedis() {
-	if RedisClient != nil {
-		RedisClient.Close()
-		hkvilog.Info("Redis连接已关闭")
+func CloseRedis() error {
+	if RedisClient == nil {
+		return nil
 	}
+	if err := RedisClient.Close(); err != nil {
+		hkvilog.Errorf("关闭Redis连接失败: %v", err)
+		return err
+	}
+	hkvilog.Info("Redis连接已关闭")
+	return nil
+}
+
+// smsCodeKey 短信验证码键，同一手机号不同用途的验证码互不影响
+func smsCodeKey(purpose, phone string) string {
+	return fmt.Sprintf("sms_code:%s:%s", purpose, phone)
 }
 
-// SetSMSCode 设置短信验证码
-func SetSMSCode(phone, code string, expiration time.Duration) error {
-	ctx := context.Background()
-	key := fmt.Sprintf("sms_code:%s", phone)
-	return RedisClient.Set(ctx, key, code, expiration).Err()
+// SetSMSCode 设置指定用途的短信验证码
+func SetSMSCode(ctx context.Context, purpose, phone, code string, expiration time.Duration) error {
+	return RedisClient.Set(ctx, smsCodeKey(purpose, phone), code, expiration).Err()
 }
 
-// GetSMSCode 获取短信验证码
-func GetSMSCode(phone string) (string, error) {
-	ctx := context.Background()
-	key := fmt.Sprintf("sms_code:%s", phone)
-	return RedisClient.Get(ctx, key).Result()
+// GetSMSCode 获取指定用途的短信验证码
+func GetSMSCode(ctx context.Context, purpose, phone string) (string, error) {
+	return RedisClient.Get(ctx, smsCodeKey(purpose, phone)).Result()
 }
 
-// DeleteSMSCode 删除短信验证码
-func DeleteSMSCode(phone string) error {
-	ctx := context.Background()
-	key := fmt.Sprintf("sms_code:%s", phone)
-	return RedisClient.Del(ctx, key).Err()
+// DeleteSMSCode 删除指定用途的短信验证码
+func DeleteSMSCode(ctx context.Context, purpose, phone string) error {
+	return RedisClient.Del(ctx, smsCodeKey(purpose, phone)).Err()
 }
 
 // SetRateLimit 设置限流
-func SetRateLimit(key string, expiration time.Duration) error {
-	ctx := context.Background()
+func SetRateLimit(ctx context.Context, key string, expiration time.Duration) error {
 	return RedisClient.Set(ctx, key, "1", expiration).Err()
 }
 
 // GetRateLimit 获取限流状态
-func GetRateLimit(key string) (int64, error) {
-	ctx := context.Background()
+func GetRateLimit(ctx context.Context, key string) (int64, error) {
 	return RedisClient.Get(ctx, key).Int64()
 }
 
 // IncrementRateLimit 增加限流计数
-func IncrementRateLimit(key string, expiration time.Duration) (int64, error) {
-	ctx := context.Background()
+func IncrementRateLimit(ctx context.Context, key string, expiration time.Duration) (int64, error) {
 	pipe := RedisClient.Pipeline()
 	incr := pipe.Incr(ctx, key)
 	pipe.Expire(ctx, key, expiration)
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// 会话键格式，网关和业务服务共用同一个Redis库中的会话（见API接口文档“共享Redis键”）
+// 两个服务各自定义了相同的格式，修改时必须同时修改，启动时通过RegisterSessionSchema互相校验
+const (
+	sessionKeyFormat      = "session:%s"       // 会话，值为会话JSON
+	userSessionsKeyFormat = "user_sessions:%d" // 用户的会话ID集合
+)
+
+// sessionKey 会话键
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf(sessionKeyFormat, sessionID)
+}
+
+// userSessionsKey 用户会话集合键
+func userSessionsKey(userID int) string {
+	return fmt.Sprintf(userSessionsKeyFormat, userID)
+}
+
+// DeleteUserSessions 删除用户的所有登录会话
+// 网关认证时会检查会话是否存在，删除后该用户已签发的访问令牌和刷新令牌全部失效
+func DeleteUserSessions(ctx context.Context, userID int) error {
+	sessionIDs, err := RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("获取会话列表失败: %v", err)
+	}
+
+	pipe := RedisClient.TxPipeline()
+	for _, sessionID := range sessionIDs {
+		pipe.Del(ctx, sessionKey(sessionID))
+	}
+	pipe.Del(ctx, userSessionsKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("删除会话失败: %v", err)
+	}
+
+	return nil
+}
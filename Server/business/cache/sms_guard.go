@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// smsVerifyAttemptsKey 验证码校验次数键
+func smsVerifyAttemptsKey(purpose, phone string) string {
+	return fmt.Sprintf("sms_verify_attempts:%s:%s", purpose, phone)
+}
+
+// smsLockKey 验证码锁定键，锁定期间该手机号不能获取和校验该用途的验证码
+func smsLockKey(purpose, phone string) string {
+	return fmt.Sprintf("sms_lock:%s:%s", purpose, phone)
+}
+
+// smsLockoutsKey 验证码锁定次数键，用于计算递增的锁定时长
+func smsLockoutsKey(purpose, phone string) string {
+	return fmt.Sprintf("sms_lockouts:%s:%s", purpose, phone)
+}
+
+// takeSMSCodeScript 读取验证码并增加校验次数，校验次数与验证码同时过期，验证码不存在时不计数
+var takeSMSCodeScript = redis.NewScript(`
+local code = redis.call("GET", KEYS[1])
+if not code then
+	return false
+end
+local attempts = redis.call("INCR", KEYS[2])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+end
+return {code, attempts}
+`)
+
+// TakeSMSCodeAttempt 读取验证码并原子地增加校验次数，返回验证码和包含本次在内的校验次数
+// 先计数再比较，并发校验时每个验证码最多被比较允许的次数，验证码不存在时返回redis.Nil
+func TakeSMSCodeAttempt(ctx context.Context, purpose, phone string) (string, int64, error) {
+	result, err := takeSMSCodeScript.Run(ctx, RedisClient, []string{smsCodeKey(purpose, phone), smsVerifyAttemptsKey(purpose, phone)}).Slice()
+	if err != nil {
+		return "", 0, err
+	}
+	if len(result) != 2 {
+		return "", 0, fmt.Errorf("验证码脚本返回格式错误")
+	}
+
+	code, _ := result[0].(string)
+	attempts, _ := result[1].(int64)
+	return code, attempts, nil
+}
+
+// GetSMSVerifyAttempts 获取当前验证码的校验次数，没有记录时返回0
+func GetSMSVerifyAttempts(ctx context.Context, purpose, phone string) (int64, error) {
+	count, err := RedisClient.Get(ctx, smsVerifyAttemptsKey(purpose, phone)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// ResetSMSVerifyAttempts 清除验证码校验次数
+func ResetSMSVerifyAttempts(ctx context.Context, purpose, phone string) error {
+	return RedisClient.Del(ctx, smsVerifyAttemptsKey(purpose, phone)).Err()
+}
+
+// LockSMSPurpose 锁定手机号指定用途的验证码，并增加锁定次数，返回增加后的锁定次数
+// lockDuration 根据锁定次数计算本次锁定时长，锁定次数在window内有效
+func LockSMSPurpose(ctx context.Context, purpose, phone string, window time.Duration, lockDuration func(lockouts int64) time.Duration) (int64, error) {
+	lockouts, err := IncrementRateLimit(ctx, smsLockoutsKey(purpose, phone), window)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := RedisClient.Set(ctx, smsLockKey(purpose, phone), lockouts, lockDuration(lockouts)).Err(); err != nil {
+		return 0, err
+	}
+	return lockouts, nil
+}
+
+// GetSMSLock 获取手机号指定用途的剩余锁定时间，未锁定时返回0
+func GetSMSLock(ctx context.Context, purpose, phone string) (time.Duration, error) {
+	ttl, err := RedisClient.PTTL(ctx, smsLockKey(purpose, phone)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// 键不存在时返回负数
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// GetSMSLockouts 获取手机号指定用途的锁定次数，没有记录时返回0
+func GetSMSLockouts(ctx context.Context, purpose, phone string) (int64, error) {
+	count, err := RedisClient.Get(ctx, smsLockoutsKey(purpose, phone)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// ClearSMSLock 解除手机号指定用途的锁定，同时清除锁定次数和验证码的校验次数
+func ClearSMSLock(ctx context.Context, purpose, phone string) error {
+	return RedisClient.Del(ctx,
+		smsLockKey(purpose, phone),
+		smsLockoutsKey(purpose, phone),
+		smsVerifyAttemptsKey(purpose, phone),
+	).Err()
+}
@@ -1,7 +1,7 @@
 package config
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
 )
@@ -12,47 +12,108 @@ type Config struct {
 	Database DatabaseConfig `json:"database"` // 数据库配置
 	Redis    RedisConfig    `json:"redis"`    // Redis配置
 	SMS      SMSConfig      `json:"sms"`      // 短信服务配置
+	Log      LogConfig      `json:"log"`      // 日志配置
+	Tracing  TracingConfig  `json:"tracing"`  // 链路追踪配置
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port string `json:"port"` // 服务器端口
-	Mode string `json:"mode"` // 运行模式
+	Port            string `json:"port"`             // 服务器端口
+	Mode            string `json:"mode"`             // 运行模式
+	MetricsPort     string `json:"metrics_port"`     // Prometheus指标端口，与服务端口分开监听，为空时不提供指标
+	ShutdownDelay   int    `json:"shutdown_delay"`   // 收到退出信号后就绪检查返回503并等待的时间（秒），让负载均衡器先摘除实例
+	ShutdownTimeout int    `json:"shutdown_timeout"` // 等待正在处理的请求完成的最长时间（秒），超时后强制关闭
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Host     string `json:"host"`     // 数据库主机
-	Port     string `json:"port"`     // 数据库端口
-	Username string `json:"username"` // 数据库用户名
-	Password string `json:"password"` // 数据库密码
-	DBName   string `json:"dbname"`   // 数据库名称
+	Host     string `json:"host"`                   // 数据库主机
+	Port     string `json:"port"`                   // 数据库端口
+	Username string `json:"username"`               // 数据库用户名
+	Password string `json:"password" secret:"true"` // 数据库密码
+	DBName   string `json:"dbname"`                 // 数据库名称
 }
 
 // RedisConfig Redis配置
 type RedisConfig struct {
-	Host     string `json:"host"`     // Redis主机
-	Port     string `json:"port"`     // Redis端口
-	Password string `json:"password"` // Redis密码
-	DB       int    `json:"db"`       // Redis数据库编号
+	Host     string `json:"host"`                   // Redis主机
+	Port     string `json:"port"`                   // Redis端口
+	Password string `json:"password" secret:"true"` // Redis密码
+	DB       int    `json:"db"`                     // Redis数据库编号
 }
 
 // SMSConfig 短信服务配置
 type SMSConfig struct {
-	AccessKeyID     string `json:"access_key_id"`     // 阿里云AccessKey ID
-	AccessKeySecret string `json:"access_key_secret"` // 阿里云AccessKey Secret
-	SignName        string `json:"sign_name"`         // 短信签名
-	TemplateCode    string `json:"template_code"`     // 短信模板代码
-	RegionID        string `json:"region_id"`         // 地域ID
+	Provider        string            `json:"provider"`                        // 短信服务提供方：console（默认，不真正发送）、aliyun、http_mock
+	FilePath        string            `json:"file_path"`                       // console：短信记录文件路径，为空时输出到日志
+	MockURL         string            `json:"mock_url"`                        // http_mock：接收短信的模拟服务地址
+	AccessKeyID     string            `json:"access_key_id" secret:"true"`     // 阿里云AccessKey ID
+	AccessKeySecret string            `json:"access_key_secret" secret:"true"` // 阿里云AccessKey Secret
+	SignName        string            `json:"sign_name"`                       // 短信签名
+	TemplateCode    string            `json:"template_code"`                   // 短信模板代码，未单独配置模板的用途使用该模板
+	Templates       map[string]string `json:"templates,omitempty"`             // 按用途配置的短信模板代码：login、register、reset、bind_phone
+	RegionID        string            `json:"region_id"`                       // 地域ID
 }
 
-// LoadConfig 加载应用配置
-func LoadConfig() *Config {
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string        `json:"level"`  // 日志级别：debug、info（默认）、warn、error
+	Format string        `json:"format"` // 日志格式：text（默认）、json
+	File   LogFileConfig `json:"file"`   // 文件日志配置
+}
+
+// LogFileConfig 文件日志配置
+type LogFileConfig struct {
+	Path       string `json:"path"`        // 日志文件路径，为空时只输出到标准输出
+	ErrorPath  string `json:"error_path"`  // 错误日志文件路径，ERROR级别的日志同时写入该文件
+	Stdout     bool   `json:"stdout"`      // 写入文件的同时输出到标准输出
+	MaxSize    int    `json:"max_size"`    // 单个文件最大大小（MB），为0时不按大小轮转
+	Rotate     string `json:"rotate"`      // 按时间轮转：hourly、daily，为空时不按时间轮转
+	MaxBackups int    `json:"max_backups"` // 保留的轮转文件数，为0时全部保留
+	Compress   bool   `json:"compress"`    // 使用gzip压缩轮转后的文件
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`      // 是否启用链路追踪，未启用时仍透传traceparent
+	ServiceName string  `json:"service_name"` // 上报的服务名称
+	Exporter    string  `json:"exporter"`     // 导出方式：otlp（默认）、stdout、file
+	Endpoint    string  `json:"endpoint"`     // OTLP/HTTP接收地址，如 localhost:4318
+	Insecure    bool    `json:"insecure"`     // OTLP使用HTTP而非HTTPS
+	FilePath    string  `json:"file_path"`    // exporter为file时写入的文件
+	SampleRatio float64 `json:"sample_ratio"` // 采样比例（0-1），上游已采样的请求始终采样
+}
+
+// LoadConfig 加载并校验应用配置
+// 未找到配置文件时使用默认配置，配置文件无法读取、解析失败、引用无法解析或校验不通过时返回错误
+func LoadConfig() (*Config, error) {
+	config, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	// 解析 ${ENV} 和 file: 引用
+	if err := resolveReferences(config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// load 依次应用默认配置、配置文件和环境变量
+func load() (*Config, error) {
 	// 默认配置
 	config := &Config{
 		Server: ServerConfig{
-			Port: "8081",
-			Mode: "debug",
+			Port:            "8081",
+			Mode:            "debug",
+			MetricsPort:     "9091",
+			ShutdownDelay:   5,
+			ShutdownTimeout: 15,
 		},
 		Database: DatabaseConfig{
 			Host:     "localhost",
@@ -68,39 +129,33 @@ func LoadConfig() *Config {
 			DB:       0,
 		},
 		SMS: SMSConfig{
+			Provider:        "console",
 			AccessKeyID:     "your-access-key-id",
 			AccessKeySecret: "your-access-key-secret",
 			SignName:        "your-sign-name",
 			TemplateCode:    "your-template-code",
 			RegionID:        "cn-hangzhou",
 		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "business",
+			Exporter:    "otlp",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
 	}
 
 	// 尝试从配置文件加载
-	if err := loadFromFile(config); err != nil {
-		// 如果配置文件不存在或读取失败，使用默认配置
-	}
+	err := loadFromFile(config)
 
 	// 从环境变量覆盖配置
 	loadFromEnv(config)
 
-	return config
-}
-
-// loadFromFile 从配置文件加载配置
-func loadFromFile(config *Config) error {
-	configFile := getConfigFile()
-	if configFile == "" {
-		return nil
-	}
-
-	file, err := os.Open(configFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	return json.NewDecoder(file).Decode(config)
+	return config, err
 }
 
 // loadFromEnv 从环境变量加载配置
@@ -112,6 +167,9 @@ func loadFromEnv(config *Config) {
 	if mode := os.Getenv("BUSINESS_MODE"); mode != "" {
 		config.Server.Mode = mode
 	}
+	if port, ok := os.LookupEnv("BUSINESS_METRICS_PORT"); ok {
+		config.Server.MetricsPort = port
+	}
 
 	// 数据库配置
 	if host := os.Getenv("DB_HOST"); host != "" {
@@ -162,23 +220,40 @@ func loadFromEnv(config *Config) {
 	if regionID := os.Getenv("SMS_REGION_ID"); regionID != "" {
 		config.SMS.RegionID = regionID
 	}
-}
+	if provider := os.Getenv("SMS_PROVIDER"); provider != "" {
+		config.SMS.Provider = provider
+	}
+	if filePath := os.Getenv("SMS_FILE_PATH"); filePath != "" {
+		config.SMS.FilePath = filePath
+	}
+	if mockURL := os.Getenv("SMS_MOCK_URL"); mockURL != "" {
+		config.SMS.MockURL = mockURL
+	}
 
-// getConfigFile 获取配置文件路径
-func getConfigFile() string {
-	// 按优先级查找配置文件
-	configFiles := []string{
-		"business-config.json",
-		"config/business-config.json",
-		"./business-config.json",
-		"./config/business-config.json",
+	// 日志配置
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		config.Log.Level = level
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.Log.Format = format
+	}
+	if file := os.Getenv("LOG_FILE"); file != "" {
+		config.Log.File.Path = file
 	}
 
-	for _, file := range configFiles {
-		if _, err := os.Stat(file); err == nil {
-			return file
+	// 链路追踪配置
+	if enabled := os.Getenv("TRACING_ENABLED"); enabled != "" {
+		config.Tracing.Enabled = enabled == "true" || enabled == "1"
+	}
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		config.Tracing.Exporter = exporter
+	}
+	if endpoint := os.Getenv("TRACING_ENDPOINT"); endpoint != "" {
+		config.Tracing.Endpoint = endpoint
+	}
+	if ratioStr := os.Getenv("TRACING_SAMPLE_RATIO"); ratioStr != "" {
+		if ratio, err := strconv.ParseFloat(ratioStr, 64); err == nil {
+			config.Tracing.SampleRatio = ratio
 		}
 	}
-
-	return ""
 }
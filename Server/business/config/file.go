@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configBaseName 默认配置文件名（不含扩展名）
+const configBaseName = "business-config"
+
+// configExtensions 支持的配置文件格式，查找默认配置文件时按此顺序
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// configFileFlag 通过--config参数指定的配置文件
+var configFileFlag string
+
+// SetConfigFile 指定配置文件路径，优先于APP_CONFIG环境变量和默认查找路径
+func SetConfigFile(file string) {
+	configFileFlag = file
+}
+
+// ConfigFiles 返回按加载顺序排列的配置文件：基础配置文件和环境配置文件
+func ConfigFiles() []string {
+	base, _ := baseConfigFile()
+	if base == "" {
+		return nil
+	}
+
+	files := []string{base}
+	if overlay := overlayFile(base); overlay != "" {
+		files = append(files, overlay)
+	}
+	return files
+}
+
+// baseConfigFile 获取基础配置文件路径，explicit表示由参数或环境变量显式指定
+func baseConfigFile() (file string, explicit bool) {
+	if configFileFlag != "" {
+		return configFileFlag, true
+	}
+	if file := os.Getenv("APP_CONFIG"); file != "" {
+		return file, true
+	}
+
+	// 依次在工作目录、工作目录下的config目录、可执行文件所在目录查找
+	dirs := []string{".", "config"}
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		dirs = append(dirs, dir, filepath.Join(dir, "config"))
+	}
+
+	for _, dir := range dirs {
+		if file := findConfigFile(dir, configBaseName); file != "" {
+			return file, false
+		}
+	}
+
+	return "", false
+}
+
+// overlayFile 获取环境配置文件路径，如 business-config.production.yaml
+// 环境由APP_ENV环境变量指定，文件与基础配置文件位于同一目录，格式可以不同
+func overlayFile(base string) string {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		return ""
+	}
+
+	name := strings.TrimSuffix(filepath.Base(base), filepath.Ext(base))
+	return findConfigFile(filepath.Dir(base), name+"."+env)
+}
+
+// findConfigFile 在目录中查找指定名称的配置文件
+func findConfigFile(dir, name string) string {
+	for _, ext := range configExtensions {
+		file := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// loadFromFile 依次加载基础配置文件和环境配置文件，后加载的字段覆盖先加载的字段
+// 显式指定的配置文件不存在时返回错误，未找到默认配置文件时使用默认配置
+func loadFromFile(config *Config) error {
+	for _, file := range ConfigFiles() {
+		if err := decodeFile(file, config); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// decodeFile 按扩展名解析配置文件
+// YAML和TOML先解析为通用结构再转换为JSON，与JSON配置文件使用相同的字段名
+func decodeFile(file string, config *Config) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".json":
+		return json.Unmarshal(data, config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s", ext)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, config)
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedValue 敏感字段脱敏后的显示值
+const redactedValue = "******"
+
+// envRefPattern 环境变量引用：${NAME} 或 ${NAME:-默认值}
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// resolveReferences 解析所有字符串配置中的引用
+//   - ${NAME}：替换为环境变量的值，可以嵌入在字符串中，${NAME:-默认值} 在变量未设置时使用默认值
+//   - file:/path：整个值替换为文件内容（去掉末尾换行），用于Docker/Kubernetes挂载的密钥文件
+func resolveReferences(config *Config) error {
+	var problems []string
+	walkStrings(reflect.ValueOf(config).Elem(), "", false, func(path string, v reflect.Value, _ bool) {
+		resolved, err := resolveValue(v.String())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			return
+		}
+		v.SetString(resolved)
+	})
+
+	if len(problems) > 0 {
+		return fmt.Errorf("解析配置引用失败:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// resolveValue 解析单个配置值中的引用
+func resolveValue(value string) (string, error) {
+	if strings.HasPrefix(value, "file:") {
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var missing []string
+	resolved := envRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := envRefPattern.FindStringSubmatch(ref)
+		if env, ok := os.LookupEnv(match[1]); ok && env != "" {
+			return env
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		missing = append(missing, match[1])
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("环境变量未设置: %s", strings.Join(missing, ", "))
+	}
+
+	return resolved, nil
+}
+
+// Redacted 返回敏感字段已脱敏的配置副本，用于日志和输出
+func (c *Config) Redacted() *Config {
+	data, _ := json.Marshal(c)
+	redacted := &Config{}
+	json.Unmarshal(data, redacted)
+
+	walkStrings(reflect.ValueOf(redacted).Elem(), "", false, func(_ string, v reflect.Value, sensitive bool) {
+		if sensitive && v.String() != "" {
+			v.SetString(redactedValue)
+		}
+	})
+	return redacted
+}
+
+// String 返回脱敏后的JSON格式配置，避免打印配置时泄露密钥
+func (c *Config) String() string {
+	data, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("配置序列化失败: %v", err)
+	}
+	return string(data)
+}
+
+// walkStrings 遍历结构体中的全部字符串字段（包括切片和map中的值），path使用配置文件中的字段名
+// 带有 secret:"true" 标签的字段及其子字段视为敏感字段
+func walkStrings(v reflect.Value, path string, sensitive bool, fn func(path string, v reflect.Value, sensitive bool)) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			walkStrings(v.Field(i), fieldPath(path, field), sensitive || isSecretField(field), fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), sensitive, fn)
+		}
+	case reflect.Map:
+		// map的值不可寻址，复制后遍历再写回
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			walkStrings(elem, fmt.Sprintf("%s.%v", path, key.Interface()), sensitive, fn)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		fn(path, v, sensitive)
+	}
+}
+
+// fieldPath 拼接字段路径
+func fieldPath(parent string, field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// isSecretField 检查字段是否标记为敏感字段
+func isSecretField(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true"
+}
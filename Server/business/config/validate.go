@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ValidationError 配置校验错误，包含发现的全部问题
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 返回全部问题，每行一个
+func (e *ValidationError) Error() string {
+	return "配置校验失败:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator 收集校验问题
+type validator struct {
+	problems []string
+}
+
+// addf 记录一个问题
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// Validate 校验配置，一次返回全部问题
+// debug模式下允许使用示例密钥，其他模式下必须配置真实密钥
+func (c *Config) Validate() error {
+	v := &validator{}
+	debug := c.Server.Mode == "debug"
+
+	// 服务器配置
+	v.checkPort("server.port", c.Server.Port)
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		v.addf("server.mode 必须为 debug、release 或 test，当前为 %q", c.Server.Mode)
+	}
+	if c.Server.MetricsPort != "" {
+		v.checkPort("server.metrics_port", c.Server.MetricsPort)
+		if c.Server.MetricsPort == c.Server.Port {
+			v.addf("server.metrics_port 不能与 server.port 相同")
+		}
+	}
+	if c.Server.ShutdownDelay < 0 || c.Server.ShutdownTimeout < 0 {
+		v.addf("server.shutdown_delay 和 server.shutdown_timeout 不能为负数")
+	}
+
+	// 数据库配置
+	if c.Database.Host == "" {
+		v.addf("database.host 不能为空")
+	}
+	v.checkPort("database.port", c.Database.Port)
+	if c.Database.Username == "" {
+		v.addf("database.username 不能为空")
+	}
+	v.checkSecret("database.password", c.Database.Password, debug)
+	if c.Database.DBName == "" {
+		v.addf("database.dbname 不能为空")
+	}
+
+	// Redis配置
+	if c.Redis.Host == "" {
+		v.addf("redis.host 不能为空")
+	}
+	v.checkPort("redis.port", c.Redis.Port)
+	if c.Redis.DB < 0 {
+		v.addf("redis.db 不能为负数")
+	}
+	// Redis允许不设置密码，设置时不能使用示例值
+	if c.Redis.Password != "" {
+		v.checkSecret("redis.password", c.Redis.Password, debug)
+	}
+
+	// 短信配置
+	v.checkSMS(&c.SMS, debug)
+
+	// 日志配置
+	switch strings.ToLower(c.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		v.addf("log.level 必须为 debug、info、warn 或 error，当前为 %q", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "", "text", "json":
+	default:
+		v.addf("log.format 必须为 text 或 json，当前为 %q", c.Log.Format)
+	}
+	switch c.Log.File.Rotate {
+	case "", "hourly", "daily":
+	default:
+		v.addf("log.file.rotate 必须为 hourly 或 daily，当前为 %q", c.Log.File.Rotate)
+	}
+	if c.Log.File.ErrorPath != "" && c.Log.File.Path == "" {
+		v.addf("log.file.error_path 需要同时配置 log.file.path")
+	}
+	if c.Log.File.MaxSize < 0 || c.Log.File.MaxBackups < 0 {
+		v.addf("log.file.max_size 和 log.file.max_backups 不能为负数")
+	}
+
+	// 链路追踪配置
+	v.checkTracing(&c.Tracing)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// checkSMS 校验短信配置，只有使用阿里云发送时才要求配置真实密钥
+func (v *validator) checkSMS(cfg *SMSConfig, debug bool) {
+	switch cfg.Provider {
+	case "aliyun":
+		v.checkSecret("sms.access_key_id", cfg.AccessKeyID, debug)
+		v.checkSecret("sms.access_key_secret", cfg.AccessKeySecret, debug)
+		v.checkSecret("sms.sign_name", cfg.SignName, debug)
+		v.checkSecret("sms.template_code", cfg.TemplateCode, debug)
+		if cfg.RegionID == "" {
+			v.addf("sms.region_id 不能为空")
+		}
+	case "console":
+	case "http_mock":
+		if u, err := url.Parse(cfg.MockURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf("sms.provider 为 http_mock 时 sms.mock_url 必须是有效的http地址，当前为 %q", cfg.MockURL)
+		}
+	default:
+		v.addf("sms.provider 必须为 console、aliyun 或 http_mock，当前为 %q", cfg.Provider)
+	}
+
+	for purpose, templateCode := range cfg.Templates {
+		switch purpose {
+		case "login", "register", "reset", "bind_phone":
+		default:
+			v.addf("sms.templates 的用途必须为 login、register、reset 或 bind_phone，当前为 %q", purpose)
+		}
+		if templateCode == "" {
+			v.addf("sms.templates.%s 不能为空", purpose)
+		}
+	}
+}
+
+// checkTracing 校验链路追踪配置，未启用时不校验
+func (v *validator) checkTracing(cfg *TracingConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	switch cfg.Exporter {
+	case "otlp":
+		if cfg.Endpoint == "" {
+			v.addf("tracing.endpoint 不能为空")
+		}
+	case "stdout":
+	case "file":
+		if cfg.FilePath == "" {
+			v.addf("tracing.exporter 为 file 时必须配置 tracing.file_path")
+		}
+	default:
+		v.addf("tracing.exporter 必须为 otlp、stdout 或 file，当前为 %q", cfg.Exporter)
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio 必须在0到1之间，当前为 %v", cfg.SampleRatio)
+	}
+}
+
+// checkPort 校验端口号
+func (v *validator) checkPort(path, port string) {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		v.addf("%s 不是有效的端口: %q", path, port)
+	}
+}
+
+// checkSecret 校验必填的密钥类配置，debug模式下允许使用示例值
+func (v *validator) checkSecret(path, value string, debug bool) {
+	switch {
+	case value == "":
+		v.addf("%s 不能为空", path)
+	case isPlaceholder(value) && !debug:
+		v.addf("%s 仍为示例值，非debug模式下必须修改", path)
+	}
+}
+
+// isPlaceholder 检查是否为示例配置中的占位值
+func isPlaceholder(value string) bool {
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "your-") {
+		return true
+	}
+	switch lower {
+	case "secret", "changeme", "change-me", "password":
+		return true
+	}
+	return false
+}
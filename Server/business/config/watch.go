@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Watch 监听配置文件（包括环境配置文件）变化和SIGHUP信号，触发时调用onReload，直到stop关闭
+// 通过定期检查文件修改时间实现，不依赖平台相关的文件通知机制
+func Watch(interval time.Duration, stop <-chan struct{}, onReload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastModified := modTimes()
+	for {
+		select {
+		case <-hup:
+			lastModified = modTimes()
+			onReload()
+		case <-ticker.C:
+			modified := modTimes()
+			if modified == lastModified {
+				continue
+			}
+			lastModified = modified
+			onReload()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// modTimes 返回所有配置文件的路径和修改时间，任一文件变化、新增或删除时结果不同
+func modTimes() string {
+	var b strings.Builder
+	for _, file := range ConfigFiles() {
+		b.WriteString(file)
+		if info, err := os.Stat(file); err == nil {
+			b.WriteString("@" + info.ModTime().String())
+		}
+		b.WriteString(";")
+	}
+	return b.String()
+}
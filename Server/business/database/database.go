@@ -5,8 +5,11 @@ import (
 	"business/utils/hkvilog"
 	"database/sql"
 	"fmt"
+	"strings"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 var DB *sql.DB
@@ -47,8 +50,8 @@ func InitDatabase(cfg *config.DatabaseConfig) error {
 		cfg.DBName,
 	)
 
-	// 连接到指定数据库
-	db, err := sql.Open("mysql", dsn)
+	// 连接到指定数据库，每条SQL都会创建span
+	db, err := otelsql.Open("mysql", dsn, otelsql.WithAttributes(semconv.DBSystemMySQL))
 	if err != nil {
 		return fmt.Errorf("数据库连接失败: %v", err)
 	}
@@ -93,11 +96,16 @@ func ensureDatabaseExists(db *sql.DB, dbName string) error {
 }
 
 // CloseDatabase 关闭数据库连接
-func CloseDatabase() {
-	if DB != nil {
-		DB.Close()
-		hkvilog.Info("数据库连接已关闭")
+func CloseDatabase() error {
+	if DB == nil {
+		return nil
 	}
+	if err := DB.Close(); err != nil {
+		hkvilog.Errorf("关闭数据库连接失败: %v", err)
+		return err
+	}
+	hkvilog.Info("数据库连接已关闭")
+	return nil
 }
 
 // CreateTables 创建数据库表
@@ -121,13 +129,18 @@ func CreateTables() error {
 		return fmt.Errorf("创建用户表失败: %v", err)
 	}
 
+	// 补充用户资料字段（已存在的表不会被CREATE TABLE IF NOT EXISTS更新）
+	if err := ensureUserProfileColumns(); err != nil {
+		return fmt.Errorf("更新用户表结构失败: %v", err)
+	}
+
 	// 创建验证码表（用于记录历史，实际验证码存储在Redis中）
 	createSMSTable := `
 	CREATE TABLE IF NOT EXISTS sms_codes (
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
 		phone VARCHAR(20) NOT NULL,
 		code VARCHAR(10) NOT NULL,
-		type ENUM('login', 'register', 'reset') NOT NULL,
+		type ENUM('login', 'register', 'reset', 'bind_phone') NOT NULL,
 		used BOOLEAN DEFAULT FALSE,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		expired_at TIMESTAMP NOT NULL,
@@ -141,6 +154,114 @@ func CreateTables() error {
 		return fmt.Errorf("创建短信验证码表失败: %v", err)
 	}
 
+	// 已存在的验证码表补充绑定手机号用途
+	if err := ensureSMSCodeTypes(); err != nil {
+		return fmt.Errorf("更新短信验证码表结构失败: %v", err)
+	}
+
+	// 创建用户角色表
+	createUserRoleTable := `
+	CREATE TABLE IF NOT EXISTS user_roles (
+		user_id BIGINT NOT NULL,
+		role VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, role),
+		INDEX idx_role (role)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+
+	_, err = DB.Exec(createUserRoleTable)
+	if err != nil {
+		return fmt.Errorf("创建用户角色表失败: %v", err)
+	}
+
+	// 创建角色权限表
+	createRolePermissionTable := `
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role VARCHAR(50) NOT NULL,
+		permission VARCHAR(100) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (role, permission)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+
+	_, err = DB.Exec(createRolePermissionTable)
+	if err != nil {
+		return fmt.Errorf("创建角色权限表失败: %v", err)
+	}
+
+	// 管理员角色默认拥有全部权限
+	_, err = DB.Exec(`INSERT IGNORE INTO role_permissions (role, permission) VALUES ('admin', '*')`)
+	if err != nil {
+		return fmt.Errorf("初始化角色权限失败: %v", err)
+	}
+
 	hkvilog.Info("数据库表创建成功")
 	return nil
 }
+
+// ensureUserProfileColumns 为用户表补充资料字段，并将updated_at精度提升到微秒，用于修改资料时的乐观并发控制
+func ensureUserProfileColumns() error {
+	columns := []struct {
+		name       string
+		definition string
+	}{
+		{"nickname", "VARCHAR(32) NOT NULL DEFAULT ''"},
+		{"avatar_url", "VARCHAR(512) NOT NULL DEFAULT ''"},
+		{"email", "VARCHAR(100) NOT NULL DEFAULT ''"},
+		{"gender", "VARCHAR(10) NOT NULL DEFAULT ''"},
+		{"birthday", "DATE NULL"},
+		{"bio", "VARCHAR(200) NOT NULL DEFAULT ''"},
+	}
+
+	for _, column := range columns {
+		var count int
+		err := DB.QueryRow(`SELECT COUNT(*) FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = ?`, column.name).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err := DB.Exec(fmt.Sprintf("ALTER TABLE users ADD COLUMN %s %s", column.name, column.definition)); err != nil {
+			return fmt.Errorf("添加字段 %s 失败: %v", column.name, err)
+		}
+		hkvilog.Infof("用户表已添加字段 %s", column.name)
+	}
+
+	// 秒级精度下同一秒内的两次修改无法区分，提升到微秒
+	var precision sql.NullInt64
+	err := DB.QueryRow(`SELECT DATETIME_PRECISION FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'users' AND COLUMN_NAME = 'updated_at'`).Scan(&precision)
+	if err != nil {
+		return err
+	}
+	if precision.Int64 < 6 {
+		_, err := DB.Exec(`ALTER TABLE users MODIFY updated_at TIMESTAMP(6) NOT NULL
+			DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6)`)
+		if err != nil {
+			return fmt.Errorf("修改updated_at精度失败: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureSMSCodeTypes 为验证码表的type字段补充bind_phone用途，与短信验证码的用途保持一致
+func ensureSMSCodeTypes() error {
+	var columnType string
+	err := DB.QueryRow(`SELECT COLUMN_TYPE FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'sms_codes' AND COLUMN_NAME = 'type'`).Scan(&columnType)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(columnType, "'bind_phone'") {
+		return nil
+	}
+
+	// 在枚举末尾追加取值只修改元数据，不会重建表
+	_, err = DB.Exec(`ALTER TABLE sms_codes MODIFY type ENUM('login', 'register', 'reset', 'bind_phone') NOT NULL`)
+	return err
+}
@@ -1,12 +1,36 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"business/cache"
+	"business/database"
+
 	"github.com/gin-gonic/gin"
 )
 
+// dependencyCheckTimeout 就绪检查中单个依赖的超时时间
+const dependencyCheckTimeout = 2 * time.Second
+
+// shuttingDown 服务是否正在关闭
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown 标记服务正在关闭，之后就绪检查始终返回503，使负载均衡器停止转发新请求
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// DependencyStatus 依赖检查结果
+type DependencyStatus struct {
+	Status    string  `json:"status"`          // ok 或 error
+	LatencyMS float64 `json:"latency_ms"`      // 检查耗时（毫秒）
+	Error     string  `json:"error,omitempty"` // 失败原因
+}
+
 // HealthCheck 健康检查处理器
 func HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -16,3 +40,81 @@ func HealthCheck(c *gin.Context) {
 		"message":   "业务服务运行正常",
 	})
 }
+
+// Liveness 存活检查，进程能处理请求即返回200，不检查依赖
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "ok",
+		"service":   "business",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// Readiness 就绪检查，检查MySQL和Redis是否可用，任一依赖不可用或服务正在关闭时返回503
+func Readiness(c *gin.Context) {
+	if shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "shutting_down",
+			"service":   "business",
+			"timestamp": time.Now().Unix(),
+		})
+		return
+	}
+
+	checks := checkDependencies(c.Request.Context(), map[string]func(ctx context.Context) error{
+		"mysql": database.DB.PingContext,
+		"redis": func(ctx context.Context) error {
+			return cache.RedisClient.Ping(ctx).Err()
+		},
+	})
+
+	status, code := "ok", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status":    status,
+		"service":   "business",
+		"timestamp": time.Now().Unix(),
+		"checks":    checks,
+	})
+}
+
+// checkDependencies 并发执行依赖检查，每项检查有独立的超时时间
+func checkDependencies(ctx context.Context, checks map[string]func(ctx context.Context) error) map[string]DependencyStatus {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]DependencyStatus, len(checks))
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+			defer cancel()
+
+			startTime := time.Now()
+			err := check(checkCtx)
+			result := DependencyStatus{
+				Status:    "ok",
+				LatencyMS: float64(time.Since(startTime).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "error"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+
+	wg.Wait()
+	return results
+}
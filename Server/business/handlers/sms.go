@@ -2,9 +2,16 @@ package handlers
 
 import (
 	"business/config"
+	"business/metrics"
+	"business/middleware"
 	"business/models"
 	"business/services"
+	"business/utils/hkvilog"
+	"database/sql"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -48,11 +55,27 @@ func (h *SMSHandler) SendSMS(c *gin.Context) {
 		return
 	}
 
+	// 验证码用途，重置密码的验证码只能通过重置密码接口申请，避免向未注册的手机号发送
+	purpose, err := services.ParseSMSPurpose(req.Purpose)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if purpose == services.SMSPurposeReset {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "重置密码验证码请通过重置密码接口申请",
+		})
+		return
+	}
+
 	// 获取客户端IP
 	clientIP := c.ClientIP()
 
 	// 检查限流
-	if err := h.smsService.CheckRateLimit(req.Phone, clientIP); err != nil {
+	if err := h.smsService.CheckRateLimit(c.Request.Context(), req.Phone, clientIP); err != nil {
+		metrics.RateLimitRejectionsTotal.WithLabelValues("sms").Inc()
 		c.JSON(http.StatusTooManyRequests, gin.H{
 			"error": err.Error(),
 		})
@@ -60,14 +83,22 @@ func (h *SMSHandler) SendSMS(c *gin.Context) {
 	}
 
 	// 发送短信验证码
-	code, err := h.smsService.SendSMSCode(req.Phone)
+	code, err := h.smsService.SendSMSCode(c.Request.Context(), req.Phone, purpose)
 	if err != nil {
+		var lockedErr *services.PhoneLockedError
+		if errors.As(err, &lockedErr) {
+			abortPhoneLocked(c, lockedErr)
+			return
+		}
+		metrics.SMSSendsTotal.WithLabelValues("failure").Inc()
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	metrics.SMSSendsTotal.WithLabelValues("success").Inc()
+
 	// 在开发环境下返回验证码（生产环境应该注释掉）
 	if gin.Mode() == gin.DebugMode {
 		c.JSON(http.StatusOK, gin.H{
@@ -102,16 +133,196 @@ func (h *SMSHandler) SMSLogin(c *gin.Context) {
 	}
 
 	// 执行短信登录
-	response, err := h.userService.LoginBySMS(req.Phone, req.Code, h.smsService)
+	response, err := h.userService.LoginBySMS(c.Request.Context(), req.Phone, req.Code, h.smsService)
 	if err != nil {
+		metrics.LoginsTotal.WithLabelValues("sms", "failure").Inc()
+		var lockedErr *services.PhoneLockedError
+		if errors.As(err, &lockedErr) {
+			abortPhoneLocked(c, lockedErr)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	metrics.LoginsTotal.WithLabelValues("sms", "success").Inc()
 	c.JSON(http.StatusOK, gin.H{
 		"message": "登录成功",
 		"data":    response,
 	})
 }
+
+// RequestPasswordReset 申请重置密码，向手机号发送验证码
+// 无论手机号是否已注册都返回相同的响应，避免泄露用户是否存在
+func (h *SMSHandler) RequestPasswordReset(c *gin.Context) {
+	var req models.PasswordResetRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 验证手机号格式
+	if err := h.smsService.ValidatePhoneNumber(req.Phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 检查限流，与发送登录验证码共用限额
+	if err := h.smsService.CheckRateLimit(c.Request.Context(), req.Phone, c.ClientIP()); err != nil {
+		metrics.RateLimitRejectionsTotal.WithLabelValues("sms").Inc()
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"message": "如果该手机号已注册，验证码将发送到该手机号",
+	}
+
+	// 只向已注册的手机号发送验证码
+	_, err := h.userService.GetUserByPhone(c.Request.Context(), req.Phone)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.Error(err)
+		return
+	}
+	if err == nil {
+		code, err := h.smsService.SendSMSCode(c.Request.Context(), req.Phone, services.SMSPurposeReset)
+		var lockedErr *services.PhoneLockedError
+		if errors.As(err, &lockedErr) {
+			// 锁定期间不发送验证码，但仍返回相同的响应，避免泄露手机号已注册
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		if err != nil {
+			metrics.SMSSendsTotal.WithLabelValues("failure").Inc()
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		metrics.SMSSendsTotal.WithLabelValues("success").Inc()
+
+		// 在开发环境下返回验证码
+		if gin.Mode() == gin.DebugMode {
+			response["code"] = code
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmPasswordReset 校验短信验证码并设置新密码，成功后注销该用户的所有登录会话
+func (h *SMSHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req models.PasswordResetConfirmRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 验证手机号格式
+	if err := h.smsService.ValidatePhoneNumber(req.Phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	err := h.userService.ResetPasswordBySMS(c.Request.Context(), req.Phone, req.Code, req.NewPassword, h.smsService)
+	if err != nil {
+		var lockedErr *services.PhoneLockedError
+		if errors.As(err, &lockedErr) {
+			abortPhoneLocked(c, lockedErr)
+			return
+		}
+		if errors.Is(err, services.ErrRevokeSessionsFailed) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码重置成功，请重新登录",
+	})
+}
+
+// GetLockout 查询手机号各用途验证码的锁定状态（管理员）
+func (h *SMSHandler) GetLockout(c *gin.Context) {
+	phone := c.Param("phone")
+	if err := h.smsService.ValidatePhoneNumber(phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	statuses, err := h.smsService.GetLockouts(c.Request.Context(), phone)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取成功",
+		"data":    statuses,
+	})
+}
+
+// ClearLockout 解除手机号的验证码锁定（管理员），可通过purpose参数只解除指定用途
+func (h *SMSHandler) ClearLockout(c *gin.Context) {
+	phone := c.Param("phone")
+	if err := h.smsService.ValidatePhoneNumber(phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var purpose services.SMSPurpose
+	if raw := c.Query("purpose"); raw != "" {
+		parsed, err := services.ParseSMSPurpose(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		purpose = parsed
+	}
+
+	if err := h.smsService.ClearLockout(c.Request.Context(), phone, purpose); err != nil {
+		c.Error(err)
+		return
+	}
+
+	hkvilog.With("request_id", c.GetString(middleware.RequestIDKey), "operator_id", c.GetInt(middleware.UserIDKey), "phone", phone, "purpose", purpose).Warn("管理员解除验证码锁定")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已解除锁定",
+	})
+}
+
+// abortPhoneLocked 返回验证码锁定响应，Retry-After为剩余锁定秒数
+func abortPhoneLocked(c *gin.Context, err *services.PhoneLockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.Remaining.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": err.Error(),
+	})
+}
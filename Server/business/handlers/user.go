@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"business/metrics"
+	"business/middleware"
 	"business/models"
 	"business/services"
+	"database/sql"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,7 +39,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// 调用服务层处理注册
-	user, err := h.userService.Register(&req)
+	user, err := h.userService.Register(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -62,16 +68,150 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// 调用服务层处理登录
-	response, err := h.userService.Login(&req)
+	response, err := h.userService.Login(c.Request.Context(), &req)
 	if err != nil {
+		metrics.LoginsTotal.WithLabelValues("password", "failure").Inc()
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	metrics.LoginsTotal.WithLabelValues("password", "success").Inc()
 	c.JSON(http.StatusOK, gin.H{
 		"message": "登录成功",
 		"data":    response,
 	})
 }
+
+// GetProfile 获取当前用户资料处理器
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	profile, err := h.userService.GetProfile(c.Request.Context(), c.GetInt(middleware.UserIDKey))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取成功",
+		"data":    profile,
+	})
+}
+
+// GetIdentity 获取当前用户最新的角色和权限，网关刷新令牌时调用
+func (h *UserHandler) GetIdentity(c *gin.Context) {
+	user, err := h.userService.GetUserIdentity(c.Request.Context(), c.GetInt(middleware.UserIDKey))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取成功",
+		"data": gin.H{
+			"user": user,
+		},
+	})
+}
+
+// UpdateProfile 修改当前用户资料处理器
+// 请求需携带最近一次读取到的updated_at，资料已被其他请求修改时返回409及最新资料
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	var req models.UpdateProfileRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	profile, err := h.userService.UpdateProfile(c.Request.Context(), c.GetInt(middleware.UserIDKey), &req)
+	if err != nil {
+		var validationErr *services.ProfileValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  validationErr.Error(),
+				"fields": validationErr.Fields,
+			})
+		case errors.Is(err, services.ErrNoProfileChanges):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrProfileConflict):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+				"data":  profile,
+			})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+		default:
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "修改成功",
+		"data":    profile,
+	})
+}
+
+// ChangePassword 修改当前用户密码处理器
+// 修改成功后所有登录会话都会被注销，包括当前会话
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err := h.userService.ChangePassword(c.Request.Context(), c.GetInt(middleware.UserIDKey), req.OldPassword, req.NewPassword)
+	if err != nil {
+		var lockedErr *services.PasswordLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.Remaining.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+		case errors.Is(err, services.ErrRevokeSessionsFailed):
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码修改成功，请重新登录",
+	})
+}
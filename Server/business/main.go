@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,44 +14,154 @@ import (
 	"business/cache"
 	"business/config"
 	"business/database"
+	"business/handlers"
+	"business/metrics"
 	"business/routes"
+	"business/tracing"
 	"business/utils/hkvilog"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// 加载配置
-	cfg := config.LoadConfig()
+	os.Exit(run())
+}
+
+// run 启动业务服务并阻塞到服务退出，返回进程退出码
+// 所有资源都通过defer释放，保证任何退出路径都会按顺序关闭依赖
+func run() (exitCode int) {
+	configFile := flag.String("config", "", "配置文件路径，支持 .json、.yaml、.yml、.toml，未指定时读取APP_CONFIG环境变量")
+	checkConfig := flag.Bool("check-config", false, "校验配置后退出")
+	printConfig := flag.Bool("print-config", false, "输出生效的配置（敏感字段已脱敏）后退出")
+	flag.Parse()
+
+	config.SetConfigFile(*configFile)
+
+	// 加载并校验配置
+	cfg, err := config.LoadConfig()
+	if *checkConfig || *printConfig {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if *printConfig {
+			fmt.Println(cfg)
+			return 0
+		}
+		fmt.Println("配置校验通过")
+		return 0
+	}
+	if err != nil {
+		hkvilog.Error("加载配置失败:", err)
+		return 1
+	}
+
+	// 应用日志配置，配置已校验过
+	logLevel, _ := hkvilog.ParseLevel(cfg.Log.Level)
+	hkvilog.SetLevel(logLevel)
+	hkvilog.SetFormat(cfg.Log.Format)
+	if cfg.Log.File.Path != "" {
+		closeLogFile, err := hkvilog.SetupFileOutput(hkvilog.FileOptions{
+			Path:       cfg.Log.File.Path,
+			ErrorPath:  cfg.Log.File.ErrorPath,
+			Stdout:     cfg.Log.File.Stdout,
+			MaxSize:    cfg.Log.File.MaxSize,
+			Rotate:     cfg.Log.File.Rotate,
+			MaxBackups: cfg.Log.File.MaxBackups,
+			Compress:   cfg.Log.File.Compress,
+		})
+		if err != nil {
+			hkvilog.Error("打开日志文件失败:", err)
+			return 1
+		}
+		defer closeLogFile()
+	}
+	hkvilog.Infof("配置文件: %v", config.ConfigFiles())
+
+	// 最后执行：其余资源都已释放
+	defer func() {
+		hkvilog.Infof("业务服务已退出，退出码: %d", exitCode)
+	}()
+
+	// 初始化链路追踪，需在连接数据库、Redis和创建路由之前完成
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		hkvilog.Error("链路追踪初始化失败:", err)
+		return 1
+	}
+	defer shutdownTracing()
 
 	// 初始化数据库
 	if err := database.InitDatabase(&cfg.Database); err != nil {
 		hkvilog.Error("数据库初始化失败:", err)
-		os.Exit(1)
+		return 1
 	}
-	defer database.CloseDatabase()
+	defer func() {
+		if err := database.CloseDatabase(); err != nil {
+			exitCode = 1
+		}
+	}()
+	metrics.RegisterDB(database.DB, cfg.Database.DBName)
 
 	// 创建数据库表
 	if err := database.CreateTables(); err != nil {
 		hkvilog.Error("创建数据库表失败:", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// 初始化Redis
 	if err := cache.InitRedis(&cfg.Redis); err != nil {
 		hkvilog.Error("Redis初始化失败:", err)
-		os.Exit(1)
+		return 1
+	}
+	defer func() {
+		if err := cache.CloseRedis(); err != nil {
+			exitCode = 1
+		}
+	}()
+	metrics.RegisterRedisPool(cache.RedisClient)
+
+	// 校验与另一个服务共用同一个Redis库且会话键格式一致，否则修改密码后无法注销会话
+	schemaCtx, cancelSchema := context.WithTimeout(context.Background(), 5*time.Second)
+	err = cache.RegisterSessionSchema(schemaCtx)
+	cancelSchema()
+	if errors.Is(err, cache.ErrSessionSchemaMissing) {
+		hkvilog.Warnf("%v，另一个服务可能尚未启动；如果持续出现，请检查两个服务的redis配置是否指向同一个库", err)
+	} else if err != nil {
+		hkvilog.Error("会话键格式校验失败:", err)
+		return 1
 	}
-	defer cache.CloseRedis()
+	stopSchema := make(chan struct{})
+	defer close(stopSchema)
+	go cache.WatchSessionSchema(stopSchema)
 
 	// 设置Gin运行模式
 	gin.SetMode(cfg.Server.Mode)
 
-	// 创建Gin引擎
-	r := gin.Default()
+	// 创建Gin引擎，请求日志和panic恢复由自定义中间件负责，保证日志格式统一
+	r := gin.New()
 
 	// 设置路由
-	routes.SetupRoutes(r, cfg)
+	rt, err := routes.SetupRoutes(r, cfg)
+	if err != nil {
+		hkvilog.Error("设置路由失败:", err)
+		return 1
+	}
+
+	// 监听配置文件变化和SIGHUP信号，热加载配置
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+	go config.Watch(2*time.Second, stopWatch, func() {
+		newCfg, err := config.LoadConfig()
+		if err == nil {
+			err = rt.Reload(newCfg)
+		}
+		if err != nil {
+			hkvilog.Errorf("重新加载配置失败，继续使用原配置: %v", err)
+			return
+		}
+		hkvilog.Info("配置已重新加载")
+	})
 
 	// 构建服务器地址
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
@@ -60,28 +172,71 @@ func main() {
 		Handler: r,
 	}
 
-	// 启动服务器（在goroutine中）
+	// 启动服务器（在goroutine中），启动失败时通知主流程退出
+	serveErr := make(chan error, 2)
 	go func() {
 		hkvilog.Infof("业务服务启动中，端口: %s", cfg.Server.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			hkvilog.Error("业务服务启动失败:", err)
-			os.Exit(1)
+			serveErr <- err
 		}
 	}()
 
+	// 指标单独监听，只应允许内网的Prometheus访问；指标端口变化需要重启后生效
+	if cfg.Server.MetricsPort != "" {
+		metricsSrv := metrics.NewServer(cfg.Server.MetricsPort)
+		defer metricsSrv.Close()
+		go func() {
+			hkvilog.Infof("指标服务启动中，端口: %s", cfg.Server.MetricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serveErr <- err
+			}
+		}()
+	}
+
 	// 等待中断信号以优雅地关闭服务器
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case err := <-serveErr:
+		hkvilog.Error("业务服务启动失败:", err)
+		return 1
+	case <-quit:
+	}
 	hkvilog.Info("正在关闭业务服务...")
 
-	// 设置5秒的超时时间
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if !shutdown(srv, cfg.Server, quit) {
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// shutdown 优雅关闭HTTP服务器，返回是否在超时前处理完所有请求
+// 先让就绪检查返回503并等待shutdown_delay，使负载均衡器停止转发新请求，期间再次收到信号时立即开始关闭
+func shutdown(srv *http.Server, cfg config.ServerConfig, quit <-chan os.Signal) bool {
+	handlers.MarkShuttingDown()
+	if delay := time.Duration(cfg.ShutdownDelay) * time.Second; delay > 0 {
+		hkvilog.Infof("就绪检查已切换为不可用，%v 后停止接收请求", delay)
+		select {
+		case <-time.After(delay):
+		case <-quit:
+		}
+	}
+
+	// 停止接收新连接，等待正在处理的请求完成，shutdown_timeout为0时不限制等待时间
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if cfg.ShutdownTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		hkvilog.Error("业务服务强制关闭:", err)
-		os.Exit(1)
+		hkvilog.Error("等待请求处理完成超时，强制关闭业务服务:", err)
+		srv.Close()
+		return false
 	}
 
-	hkvilog.Info("业务服务已退出")
+	hkvilog.Info("业务服务已停止接收请求")
+	return true
 }
@@ -13,8 +13,8 @@ func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				// 记录panic错误
-				hkvilog.Errorf("Panic recovered: %v", err)
+				// 记录panic错误及堆栈
+				hkvilog.With("request_id", c.GetString(RequestIDKey), "method", c.Request.Method, "path", c.Request.URL.Path).Recovered(err)
 
 				// 返回内部服务器错误
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -33,7 +33,7 @@ func ErrorHandler() gin.HandlerFunc {
 			err := c.Errors.Last()
 
 			// 记录错误
-			hkvilog.Errorf("Request error: %v", err)
+			hkvilog.With("request_id", c.GetString(RequestIDKey), "method", c.Request.Method, "path", c.Request.URL.Path, "error", err).Error("请求处理失败")
 
 			// 如果还没有响应，返回错误信息
 			if !c.Writer.Written() {
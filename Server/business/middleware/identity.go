@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserIDHeader 网关转发的已认证用户ID头
+const UserIDHeader = "X-User-ID"
+
+// UserIDKey 上下文中保存用户ID的键
+const UserIDKey = "user_id"
+
+// UserRolesHeader 网关转发的用户角色头，多个角色以逗号分隔
+const UserRolesHeader = "X-User-Roles"
+
+// RequireUser 用户身份中间件
+// 读取网关认证后注入的X-User-ID，缺失或不合法时返回401。业务服务只应通过网关访问，网关会清除客户端伪造的身份头
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.Atoi(c.GetHeader(UserIDHeader))
+		if err != nil || userID <= 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "未登录",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(UserIDKey, userID)
+		c.Next()
+	}
+}
+
+// RequireRole 角色校验中间件，用户必须拥有任一指定角色，否则返回403
+// 角色来自网关转发的X-User-Roles，需要在RequireUser之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, granted := range strings.Split(c.GetHeader(UserRolesHeader), ",") {
+			for _, role := range roles {
+				if strings.TrimSpace(granted) == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "权限不足",
+		})
+		c.Abort()
+	}
+}
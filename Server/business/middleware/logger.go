@@ -6,8 +6,12 @@ import (
 	"business/utils/hkvilog"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// RouteNameKey 上下文中保存代理路由名称的键，用于请求日志
+const RouteNameKey = "route_name"
+
 // LoggerMiddleware 日志中间件
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -20,21 +24,40 @@ func LoggerMiddleware() gin.HandlerFunc {
 		// 计算处理时间
 		duration := time.Since(startTime)
 
-		// 获取请求信息
-		clientIP := c.ClientIP()
-		method := c.Request.Method
-		path := c.Request.URL.Path
-		statusCode := c.Writer.Status()
-		userAgent := c.Request.UserAgent()
-
-		// 记录日志
-		hkvilog.Infof("[%s] %s %s %d %v \"%s\"",
-			clientIP,
-			method,
-			path,
-			statusCode,
-			duration,
-			userAgent,
-		)
+		// 记录结构化日志，user_id和route在认证和路由匹配后才有值
+		fields := []interface{}{
+			"request_id", c.GetString(RequestIDKey),
+			"client_ip", c.ClientIP(),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", requestRoute(c),
+			"status", c.Writer.Status(),
+			"latency", duration,
+			"user_agent", c.Request.UserAgent(),
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			fields = append(fields, "user_id", userID)
+		}
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+			fields = append(fields, "trace_id", spanContext.TraceID().String())
+		}
+
+		logger := hkvilog.With(fields...)
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			logger.Error("请求处理完成")
+		case status >= 400:
+			logger.Warn("请求处理完成")
+		default:
+			logger.Info("请求处理完成")
+		}
+	}
+}
+
+// requestRoute 返回请求匹配的路由，代理请求返回代理路由名称
+func requestRoute(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
 	}
+	return c.GetString(RouteNameKey)
 }
@@ -35,15 +35,63 @@ type SMSLoginRequest struct {
 
 // SendSMSRequest 发送短信验证码请求
 type SendSMSRequest struct {
+	Phone   string `json:"phone" binding:"required"` // 手机号
+	Purpose string `json:"purpose,omitempty"`        // 验证码用途：login（默认）、register、bind_phone
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`               // 原密码
+	NewPassword string `json:"new_password" binding:"required,min=6,max=100"` // 新密码
+}
+
+// PasswordResetRequest 申请重置密码请求
+type PasswordResetRequest struct {
 	Phone string `json:"phone" binding:"required"` // 手机号
 }
 
+// PasswordResetConfirmRequest 确认重置密码请求
+type PasswordResetConfirmRequest struct {
+	Phone       string `json:"phone" binding:"required"`                      // 手机号
+	Code        string `json:"code" binding:"required"`                       // 验证码
+	NewPassword string `json:"new_password" binding:"required,min=6,max=100"` // 新密码
+}
+
 // UserResponse 用户响应
 type UserResponse struct {
+	ID          int       `json:"id"`                    // 用户ID
+	Username    string    `json:"username"`              // 用户名
+	Phone       string    `json:"phone"`                 // 手机号
+	Roles       []string  `json:"roles,omitempty"`       // 角色列表
+	Permissions []string  `json:"permissions,omitempty"` // 权限列表
+	CreatedAt   time.Time `json:"created_at"`            // 创建时间
+}
+
+// UserProfile 用户资料
+type UserProfile struct {
 	ID        int       `json:"id"`         // 用户ID
 	Username  string    `json:"username"`   // 用户名
 	Phone     string    `json:"phone"`      // 手机号
+	Nickname  string    `json:"nickname"`   // 昵称
+	AvatarURL string    `json:"avatar_url"` // 头像地址
+	Email     string    `json:"email"`      // 邮箱
+	Gender    string    `json:"gender"`     // 性别：male、female、other，为空表示未设置
+	Birthday  string    `json:"birthday"`   // 生日（YYYY-MM-DD），为空表示未设置
+	Bio       string    `json:"bio"`        // 个人简介
 	CreatedAt time.Time `json:"created_at"` // 创建时间
+	UpdatedAt time.Time `json:"updated_at"` // 更新时间，修改资料时需原样带回用于并发控制
+}
+
+// UpdateProfileRequest 修改用户资料请求
+// 未提供的字段保持不变，空字符串表示清空该字段
+type UpdateProfileRequest struct {
+	Nickname  *string   `json:"nickname"`                      // 昵称
+	AvatarURL *string   `json:"avatar_url"`                    // 头像地址
+	Email     *string   `json:"email"`                         // 邮箱
+	Gender    *string   `json:"gender"`                        // 性别
+	Birthday  *string   `json:"birthday"`                      // 生日（YYYY-MM-DD）
+	Bio       *string   `json:"bio"`                           // 个人简介
+	UpdatedAt time.Time `json:"updated_at" binding:"required"` // 读取资料时返回的更新时间
 }
 
 // LoginResponse 登录响应（业务服务不生成token，只返回用户信息）
@@ -51,6 +99,18 @@ type LoginResponse struct {
 	User UserResponse `json:"user"` // 用户信息
 }
 
+// SMSLockoutStatus 手机号某一用途验证码的锁定状态
+type SMSLockoutStatus struct {
+	Phone            string     `json:"phone"`                  // 手机号
+	Purpose          string     `json:"purpose"`                // 验证码用途
+	Locked           bool       `json:"locked"`                 // 是否处于锁定中
+	RemainingSeconds int64      `json:"remaining_seconds"`      // 剩余锁定时间（秒）
+	LockedUntil      *time.Time `json:"locked_until,omitempty"` // 锁定结束时间
+	Lockouts         int64      `json:"lockouts"`               // 24小时内的锁定次数，决定下次锁定时长
+	FailedAttempts   int64      `json:"failed_attempts"`        // 当前验证码的错误次数
+	MaxAttempts      int64      `json:"max_attempts"`           // 每个验证码允许的错误次数
+}
+
 // SMSResponse 短信响应
 type SMSResponse struct {
 	Message string `json:"message"` // 响应消息
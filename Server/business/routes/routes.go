@@ -4,46 +4,74 @@ import (
 	"business/config"
 	"business/handlers"
 	"business/middleware"
-	"business/utils/hkvilog"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-// SetupRoutes 设置路由
-func SetupRoutes(r *gin.Engine, cfg *config.Config) {
+// SetupRoutes 设置路由，返回支持配置热加载的运行时组件
+func SetupRoutes(r *gin.Engine, cfg *config.Config) (*Runtime, error) {
 	// 使用中间件
-	r.Use(middleware.LoggerMiddleware()) // 日志中间件
-	r.Use(middleware.ErrorHandler())     // 错误处理中间件
+	r.Use(middleware.RequestIDMiddleware())            // 请求ID中间件
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName)) // 链路追踪中间件
+	r.Use(middleware.MetricsMiddleware())              // 指标中间件
+	r.Use(middleware.LoggerMiddleware())               // 日志中间件
+	r.Use(middleware.ErrorHandler())                   // 错误处理中间件
 
 	// 创建处理器实例
 	userHandler := handlers.NewUserHandler()
-	smsHandler, err := handlers.NewSMSHandler(&cfg.SMS)
+	s, err := newSnapshot(cfg)
 	if err != nil {
-		hkvilog.Errorf("创建短信处理器失败: %v", err)
-		return
+		return nil, err
 	}
+	s.applyLog()
+
+	rt := &Runtime{}
+	rt.current.Store(s)
 
 	// API路由组
 	api := r.Group("/api")
 	{
 		// 健康检查接口
 		api.GET("/health", handlers.HealthCheck)
+		api.GET("/health/live", handlers.Liveness)   // 存活检查
+		api.GET("/health/ready", handlers.Readiness) // 就绪检查
 
 		// 认证相关接口
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", userHandler.Register) // 用户注册
-			auth.POST("/login", userHandler.Login)       // 用户登录
+			auth.POST("/register", userHandler.Register)                                                                                     // 用户注册
+			auth.POST("/login", userHandler.Login)                                                                                           // 用户登录
+			auth.POST("/password/reset/request", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsHandler.RequestPasswordReset })) // 申请重置密码
+			auth.POST("/password/reset/confirm", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsHandler.ConfirmPasswordReset })) // 确认重置密码
 		}
 
 		// 短信相关接口
 		sms := api.Group("/sms")
 		{
-			sms.POST("/send", smsHandler.SendSMS)   // 发送短信验证码
-			sms.POST("/login", smsHandler.SMSLogin) // 短信验证码登录
+			sms.POST("/send", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsHandler.SendSMS }))   // 发送短信验证码
+			sms.POST("/login", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsHandler.SMSLogin })) // 短信验证码登录
+		}
+
+		// 用户相关接口，需要网关认证后转发的用户身份
+		users := api.Group("/users", middleware.RequireUser())
+		{
+			users.GET("/me", userHandler.GetProfile)               // 获取当前用户资料
+			users.PATCH("/me", userHandler.UpdateProfile)          // 修改当前用户资料
+			users.POST("/me/password", userHandler.ChangePassword) // 修改密码
+			users.GET("/me/identity", userHandler.GetIdentity)     // 获取当前用户的角色和权限
+		}
+
+		// 管理接口，仅管理员可访问
+		admin := api.Group("/admin", middleware.RequireUser(), middleware.RequireRole("admin"))
+		{
+			admin.GET("/sms/lockouts/:phone", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsHandler.GetLockout }))      // 查询验证码锁定状态
+			admin.DELETE("/sms/lockouts/:phone", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsHandler.ClearLockout })) // 解除验证码锁定
 		}
 
 		// 其他业务接口可以在这里添加
-		// 例如：用户信息管理、订单管理等
+		// 例如：订单管理等
 	}
+
+	return rt, nil
 }
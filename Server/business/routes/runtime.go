@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"business/config"
+	"business/handlers"
+	"business/utils/hkvilog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Runtime 可热加载的运行时组件
+// 路由只注册一次，请求处理时从当前快照中取出处理器，配置重新加载后整体替换快照
+type Runtime struct {
+	mu      sync.Mutex // 串行化配置重新加载
+	current atomic.Pointer[snapshot]
+}
+
+// snapshot 由一份配置构建的全部处理器
+type snapshot struct {
+	cfg        *config.Config
+	logLevel   int
+	smsHandler *handlers.SMSHandler
+}
+
+// newSnapshot 根据配置构建处理器，任一组件构建失败时返回错误
+func newSnapshot(cfg *config.Config) (*snapshot, error) {
+	logLevel, err := hkvilog.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	smsHandler, err := handlers.NewSMSHandler(&cfg.SMS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot{
+		cfg:        cfg,
+		logLevel:   logLevel,
+		smsHandler: smsHandler,
+	}, nil
+}
+
+// applyLog 应用日志级别和格式
+func (s *snapshot) applyLog() {
+	hkvilog.SetLevel(s.logLevel)
+	hkvilog.SetFormat(s.cfg.Log.Format)
+}
+
+// Config 返回当前生效的配置
+func (rt *Runtime) Config() *config.Config {
+	return rt.current.Load().cfg
+}
+
+// Reload 应用新配置，构建失败时保留原配置并返回错误
+// 短信配置和日志级别立即生效，服务端口、数据库和Redis配置需要重启
+func (rt *Runtime) Reload(cfg *config.Config) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	old := rt.current.Load()
+	changes := config.Diff(old.cfg, cfg)
+	if len(changes) == 0 {
+		hkvilog.Info("配置未变化")
+		return nil
+	}
+
+	next, err := newSnapshot(cfg)
+	if err != nil {
+		return err
+	}
+
+	next.applyLog()
+	rt.current.Store(next)
+
+	for _, change := range changes {
+		if config.RequiresRestart(change) {
+			hkvilog.Infof("配置变更（需要重启后生效）: %s", change)
+			continue
+		}
+		hkvilog.Infof("配置变更: %s", change)
+	}
+
+	return nil
+}
+
+// dynamic 返回从当前快照中取出处理器的处理函数
+func (rt *Runtime) dynamic(pick func(s *snapshot) gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pick(rt.current.Load())(c)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"business/cache"
+	"business/database"
+	"business/utils"
+	"business/utils/hkvilog"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// maxPasswordAttempts 修改密码时允许连续输错原密码的次数，达到后锁定修改密码
+	maxPasswordAttempts = 5
+
+	// passwordAttemptWindow 原密码错误次数的统计周期
+	passwordAttemptWindow = 15 * time.Minute
+)
+
+// ErrWrongPassword 原密码错误
+var ErrWrongPassword = errors.New("原密码错误")
+
+// PasswordLockedError 原密码错误次数过多，修改密码已被锁定
+type PasswordLockedError struct {
+	Remaining time.Duration // 剩余锁定时间
+}
+
+// Error 实现error接口
+func (e *PasswordLockedError) Error() string {
+	return fmt.Sprintf("原密码错误次数过多，请%d分钟后再试", int(math.Ceil(e.Remaining.Minutes())))
+}
+
+// ErrRevokeSessionsFailed 密码已修改，但注销登录会话失败
+var ErrRevokeSessionsFailed = errors.New("密码已修改，但注销登录设备失败，请稍后在会话管理中手动注销")
+
+// ChangePassword 修改密码，成功后注销该用户的所有登录会话
+// 连续输错原密码maxPasswordAttempts次后锁定修改密码，锁定时长逐次递增，锁定期间返回*PasswordLockedError
+func (s *UserService) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// 通过短信验证码注册的用户没有密码，只能通过短信重置
+	if user.Password == "" {
+		return errors.New("当前账号未设置密码，请通过短信验证码重置密码")
+	}
+
+	if err := checkPasswordLock(ctx, userID); err != nil {
+		return err
+	}
+
+	// 先计数再校验，防止并发请求绕过次数限制
+	attempts, err := cache.TakePasswordAttempt(ctx, userID, passwordAttemptWindow)
+	if err != nil {
+		hkvilog.Errorf("记录原密码校验次数失败: %v", err)
+		return errors.New("修改密码失败，请稍后再试")
+	}
+	if attempts > maxPasswordAttempts {
+		// 并发请求在锁定前已经计数，按已有的锁定处理
+		if err := checkPasswordLock(ctx, userID); err != nil {
+			return err
+		}
+		return &PasswordLockedError{Remaining: lockDuration(1)}
+	}
+
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	valid := utils.CheckPassword(oldPassword, user.Password)
+	span.End()
+	if !valid {
+		return recordPasswordFailure(ctx, userID, attempts)
+	}
+	if err := cache.ResetPasswordAttempts(ctx, userID); err != nil {
+		hkvilog.Errorf("清除原密码校验次数失败: %v", err)
+	}
+
+	if oldPassword == newPassword {
+		return errors.New("新密码不能与原密码相同")
+	}
+
+	return s.setPassword(ctx, userID, newPassword)
+}
+
+// checkPasswordLock 检查用户修改密码是否被锁定，锁定时返回*PasswordLockedError
+func checkPasswordLock(ctx context.Context, userID int) error {
+	remaining, err := cache.GetPasswordLock(ctx, userID)
+	if err != nil {
+		hkvilog.Errorf("检查修改密码锁定状态失败: %v", err)
+		return errors.New("检查锁定状态失败")
+	}
+	if remaining > 0 {
+		return &PasswordLockedError{Remaining: remaining}
+	}
+	return nil
+}
+
+// recordPasswordFailure 处理一次原密码错误，attempts为包含本次在内的校验次数
+// 达到最大错误次数时锁定修改密码，锁定时长逐次递增
+func recordPasswordFailure(ctx context.Context, userID int, attempts int64) error {
+	if attempts < maxPasswordAttempts {
+		return fmt.Errorf("%w，还可尝试%d次", ErrWrongPassword, maxPasswordAttempts-attempts)
+	}
+
+	lockouts, err := cache.LockPassword(ctx, userID, lockoutWindow, lockDuration)
+	if err != nil {
+		hkvilog.Errorf("锁定修改密码失败: %v", err)
+		return ErrWrongPassword
+	}
+	duration := lockDuration(lockouts)
+	hkvilog.Warnf("安全事件: 修改密码时原密码错误次数过多，锁定修改密码 user_id=%d lockouts=%d duration=%v", userID, lockouts, duration)
+
+	return &PasswordLockedError{Remaining: duration}
+}
+
+// ResetPasswordBySMS 通过短信验证码重置密码，成功后注销该用户的所有登录会话
+func (s *UserService) ResetPasswordBySMS(ctx context.Context, phone, code, newPassword string, smsService *SMSService) error {
+	valid, err := smsService.VerifySMSCode(ctx, phone, code, SMSPurposeReset)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("验证码错误")
+	}
+
+	user, err := s.GetUserByPhone(ctx, phone)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("验证码错误")
+		}
+		return err
+	}
+
+	return s.setPassword(ctx, user.ID, newPassword)
+}
+
+// setPassword 保存新密码并注销用户的所有登录会话
+func (s *UserService) setPassword(ctx context.Context, userID int, password string) error {
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	hashedPassword, err := utils.HashPassword(password)
+	span.End()
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET password = ? WHERE id = ?`
+	if _, err := database.DB.ExecContext(ctx, query, hashedPassword, userID); err != nil {
+		return err
+	}
+
+	// 密码修改后已登录的设备需要重新登录
+	if err := cache.DeleteUserSessions(ctx, userID); err != nil {
+		hkvilog.Errorf("修改密码后注销登录会话失败 user_id=%d: %v", userID, err)
+		return ErrRevokeSessionsFailed
+	}
+
+	return nil
+}
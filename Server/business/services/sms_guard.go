@@ -0,0 +1,148 @@
+package services
+
+import (
+	"business/cache"
+	"business/models"
+	"business/utils/hkvilog"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// smsCodeExpiration 验证码有效期
+	smsCodeExpiration = 5 * time.Minute
+
+	// maxSMSVerifyAttempts 每个验证码允许的错误次数，达到后验证码失效
+	maxSMSVerifyAttempts = 5
+
+	// lockoutWindow 锁定次数的统计周期，周期内锁定时长逐次递增，验证码和修改密码共用
+	lockoutWindow = 24 * time.Hour
+)
+
+// smsPurposes 全部验证码用途
+var smsPurposes = []SMSPurpose{SMSPurposeLogin, SMSPurposeRegister, SMSPurposeReset, SMSPurposeBindPhone}
+
+// lockDurations 第N次锁定的时长，超过后使用最后一个，验证码和修改密码共用
+var lockDurations = []time.Duration{
+	15 * time.Minute,
+	time.Hour,
+	24 * time.Hour,
+}
+
+// PhoneLockedError 手机号该用途的验证码已被锁定
+type PhoneLockedError struct {
+	Remaining time.Duration // 剩余锁定时间
+}
+
+// Error 实现error接口
+func (e *PhoneLockedError) Error() string {
+	return fmt.Sprintf("验证码错误次数过多，请%d分钟后再试", int(math.Ceil(e.Remaining.Minutes())))
+}
+
+// lockDuration 根据锁定次数计算锁定时长
+func lockDuration(lockouts int64) time.Duration {
+	index := int(lockouts) - 1
+	if index >= len(lockDurations) {
+		index = len(lockDurations) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	return lockDurations[index]
+}
+
+// checkLock 检查手机号该用途的验证码是否被锁定，锁定时返回*PhoneLockedError
+func (s *SMSService) checkLock(ctx context.Context, phone string, purpose SMSPurpose) error {
+	remaining, err := cache.GetSMSLock(ctx, string(purpose), phone)
+	if err != nil {
+		hkvilog.Errorf("检查验证码锁定状态失败: %v", err)
+		return fmt.Errorf("检查锁定状态失败")
+	}
+	if remaining > 0 {
+		return &PhoneLockedError{Remaining: remaining}
+	}
+	return nil
+}
+
+// recordVerifyFailure 处理一次验证码错误，attempts为包含本次在内的校验次数
+// 达到最大错误次数时使验证码失效，并锁定手机号该用途的验证码，锁定时长逐次递增
+// 所有用途都会锁定，否则攻击者可以每分钟重新获取验证码继续猜测，重置密码尤其如此
+func (s *SMSService) recordVerifyFailure(ctx context.Context, phone string, purpose SMSPurpose, attempts int64) error {
+	if attempts < maxSMSVerifyAttempts {
+		return fmt.Errorf("验证码错误，还可尝试%d次", maxSMSVerifyAttempts-attempts)
+	}
+
+	// 达到最大错误次数，验证码失效
+	s.invalidateCode(ctx, phone, purpose)
+
+	lockouts, err := cache.LockSMSPurpose(ctx, string(purpose), phone, lockoutWindow, lockDuration)
+	if err != nil {
+		hkvilog.Errorf("锁定验证码失败: %v", err)
+		return fmt.Errorf("验证码错误次数过多，请重新获取验证码")
+	}
+	duration := lockDuration(lockouts)
+	hkvilog.Warnf("安全事件: 短信验证码错误次数过多，锁定验证码 phone=%s purpose=%s lockouts=%d duration=%v", phone, purpose, lockouts, duration)
+
+	return &PhoneLockedError{Remaining: duration}
+}
+
+// invalidateCode 删除验证码及其校验次数
+func (s *SMSService) invalidateCode(ctx context.Context, phone string, purpose SMSPurpose) {
+	if err := cache.DeleteSMSCode(ctx, string(purpose), phone); err != nil {
+		hkvilog.Errorf("删除验证码失败: %v", err)
+	}
+	if err := cache.ResetSMSVerifyAttempts(ctx, string(purpose), phone); err != nil {
+		hkvilog.Errorf("清除验证码校验次数失败: %v", err)
+	}
+}
+
+// GetLockouts 获取手机号各用途验证码的锁定状态
+func (s *SMSService) GetLockouts(ctx context.Context, phone string) ([]*models.SMSLockoutStatus, error) {
+	statuses := make([]*models.SMSLockoutStatus, 0, len(smsPurposes))
+	for _, purpose := range smsPurposes {
+		remaining, err := cache.GetSMSLock(ctx, string(purpose), phone)
+		if err != nil {
+			return nil, err
+		}
+		lockouts, err := cache.GetSMSLockouts(ctx, string(purpose), phone)
+		if err != nil {
+			return nil, err
+		}
+		attempts, err := cache.GetSMSVerifyAttempts(ctx, string(purpose), phone)
+		if err != nil {
+			return nil, err
+		}
+
+		status := &models.SMSLockoutStatus{
+			Phone:          phone,
+			Purpose:        string(purpose),
+			Locked:         remaining > 0,
+			Lockouts:       lockouts,
+			FailedAttempts: attempts,
+			MaxAttempts:    maxSMSVerifyAttempts,
+		}
+		if remaining > 0 {
+			status.RemainingSeconds = int64(math.Ceil(remaining.Seconds()))
+			lockedUntil := time.Now().Add(remaining)
+			status.LockedUntil = &lockedUntil
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// ClearLockout 解除手机号验证码的锁定，并清零锁定次数和校验次数，purpose为空时解除全部用途
+func (s *SMSService) ClearLockout(ctx context.Context, phone string, purpose SMSPurpose) error {
+	purposes := smsPurposes
+	if purpose != "" {
+		purposes = []SMSPurpose{purpose}
+	}
+	for _, p := range purposes {
+		if err := cache.ClearSMSLock(ctx, string(p), phone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,114 +3,155 @@ package services
 import (
 	"business/cache"
 	"business/config"
+	"business/sms"
 	"business/utils/hkvilog"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"strings"
 	"time"
 
-	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
-	dysmsapi20170525 "github.com/alibabacloud-go/dysmsapi-20170525/v3/client"
-	"github.com/alibabacloud-go/tea/tea"
+	"github.com/go-redis/redis/v8"
 )
 
+// SMSPurpose 短信验证码用途，与sms_codes表的type字段取值一致
+type SMSPurpose string
+
+const (
+	SMSPurposeLogin     SMSPurpose = "login"      // 短信登录
+	SMSPurposeRegister  SMSPurpose = "register"   // 注册
+	SMSPurposeReset     SMSPurpose = "reset"      // 重置密码
+	SMSPurposeBindPhone SMSPurpose = "bind_phone" // 绑定手机号
+)
+
+// ParseSMSPurpose 解析验证码用途，为空时默认为短信登录
+func ParseSMSPurpose(purpose string) (SMSPurpose, error) {
+	switch p := SMSPurpose(purpose); p {
+	case "":
+		return SMSPurposeLogin, nil
+	case SMSPurposeLogin, SMSPurposeRegister, SMSPurposeReset, SMSPurposeBindPhone:
+		return p, nil
+	default:
+		return "", fmt.Errorf("不支持的验证码用途: %s", purpose)
+	}
+}
+
 // SMSService 短信服务
 type SMSService struct {
-	client *dysmsapi20170525.Client
+	sender sms.Sender
 	config *config.SMSConfig
 }
 
-// NewSMSService 创建短信服务实例
+// NewSMSService 创建短信服务实例，按配置选择短信发送器
 func NewSMSService(cfg *config.SMSConfig) (*SMSService, error) {
-	config := &openapi.Config{
-		AccessKeyId:     tea.String(cfg.AccessKeyID),
-		AccessKeySecret: tea.String(cfg.AccessKeySecret),
-	}
-
-	// 访问的域名
-	config.Endpoint = tea.String("dysmsapi.aliyuncs.com")
-
-	client, err := dysmsapi20170525.NewClient(config)
+	sender, err := sms.NewSender(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("创建阿里云短信客户端失败: %v", err)
+		return nil, err
 	}
 
 	return &SMSService{
-		client: client,
+		sender: sender,
 		config: cfg,
 	}, nil
 }
 
-// GenerateSMSCode 生成6位数字验证码
-func (s *SMSService) GenerateSMSCode() string {
-	rand.Seed(time.Now().UnixNano())
-	code := rand.Intn(900000) + 100000 // 生成100000-999999之间的数字
-	return fmt.Sprintf("%06d", code)
+// GenerateSMSCode 使用crypto/rand生成6位数字验证码
+func (s *SMSService) GenerateSMSCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// templateCode 获取用途对应的短信模板，未单独配置时使用默认模板
+func (s *SMSService) templateCode(purpose SMSPurpose) string {
+	if templateCode := s.config.Templates[string(purpose)]; templateCode != "" {
+		return templateCode
+	}
+	return s.config.TemplateCode
 }
 
-// SendSMSCode 发送短信验证码
-func (s *SMSService) SendSMSCode(phone string) (string, error) {
-	// 生成验证码，暂时写死为201707
-	code := "201707"
-	//code := s.GenerateSMSCode()
-	//
-	// 创建短信请求
-	//sendSmsRequest := &dysmsapi20170525.SendSmsRequest{
-	//	PhoneNumbers:  tea.String(phone),
-	//	SignName:      tea.String(s.config.SignName),
-	//	TemplateCode:  tea.String(s.config.TemplateCode),
-	//	TemplateParam: tea.String(fmt.Sprintf(`{"code":"%s"}`, code)),
-	//}
-	//
-	//// 发送短信
-	//response, err := s.client.SendSms(sendSmsRequest)
-	//if err != nil {
-	//	return "", fmt.Errorf("发送短信失败: %v", err)
-	//}
-	//
-	//// 检查发送结果
-	//if *response.Body.Code != "OK" {
-	//	return "", fmt.Errorf("短信发送失败: %s", *response.Body.Message)
-	//}
-
-	// 将验证码存储到Redis，5分钟过期
-	err := cache.SetSMSCode(phone, code, 5*time.Minute)
+// SendSMSCode 发送指定用途的短信验证码，验证码只能用于相同用途的校验
+func (s *SMSService) SendSMSCode(ctx context.Context, phone string, purpose SMSPurpose) (string, error) {
+	// 锁定期间不再发送该用途的验证码
+	if err := s.checkLock(ctx, phone, purpose); err != nil {
+		return "", err
+	}
+
+	code, err := s.GenerateSMSCode()
+	if err != nil {
+		hkvilog.Errorf("生成验证码失败: %v", err)
+		return "", fmt.Errorf("生成验证码失败")
+	}
+
+	// 先将验证码存储到Redis再发送，避免用户收到短信时验证码尚未保存；新验证码重新计算错误次数
+	err = cache.SetSMSCode(ctx, string(purpose), phone, code, smsCodeExpiration)
 	if err != nil {
 		hkvilog.Errorf("存储验证码失败: %v", err)
 		return "", fmt.Errorf("存储验证码失败")
 	}
+	if err := cache.ResetSMSVerifyAttempts(ctx, string(purpose), phone); err != nil {
+		hkvilog.Errorf("清除验证码校验次数失败: %v", err)
+	}
 
-	hkvilog.Infof("短信验证码已发送到 %s", phone)
+	// 发送短信，失败时删除已保存的验证码
+	err = s.sender.Send(ctx, &sms.Message{
+		Phone:        phone,
+		SignName:     s.config.SignName,
+		TemplateCode: s.templateCode(purpose),
+		Params:       map[string]string{"code": code},
+	})
+	if err != nil {
+		hkvilog.Errorf("发送短信验证码失败: %v", err)
+		s.invalidateCode(context.WithoutCancel(ctx), phone, purpose)
+		return "", fmt.Errorf("发送短信失败，请稍后再试")
+	}
+
+	hkvilog.Infof("短信验证码已发送到 %s，用途: %s", phone, purpose)
 	return code, nil
 }
 
-// VerifySMSCode 验证短信验证码
-func (s *SMSService) VerifySMSCode(phone, code string) (bool, error) {
-	// 从Redis获取存储的验证码
-	storedCode, err := cache.GetSMSCode(phone)
+// VerifySMSCode 验证指定用途的短信验证码，其他用途的验证码不会通过校验
+// 每个验证码最多允许错误maxSMSVerifyAttempts次，之后验证码失效并逐次延长该用途的锁定时间，锁定期间返回*PhoneLockedError
+func (s *SMSService) VerifySMSCode(ctx context.Context, phone, code string, purpose SMSPurpose) (bool, error) {
+	if err := s.checkLock(ctx, phone, purpose); err != nil {
+		return false, err
+	}
+
+	// 从Redis获取存储的验证码，并在比较之前原子地计入本次校验，避免并发猜测绕过次数限制
+	storedCode, attempts, err := cache.TakeSMSCodeAttempt(ctx, string(purpose), phone)
 	if err != nil {
+		if err != redis.Nil {
+			hkvilog.Errorf("读取验证码失败: %v", err)
+		}
 		return false, fmt.Errorf("验证码不存在或已过期")
 	}
 
-	// 比较验证码
-	if storedCode != code {
-		return false, fmt.Errorf("验证码错误")
+	// 超过允许次数的并发请求直接拒绝，验证码已由达到上限的请求删除
+	if attempts > maxSMSVerifyAttempts {
+		s.invalidateCode(ctx, phone, purpose)
+		return false, fmt.Errorf("验证码错误次数过多，请重新获取验证码")
 	}
 
-	// 验证成功后删除验证码
-	err = cache.DeleteSMSCode(phone)
-	if err != nil {
-		hkvilog.Errorf("删除验证码失败: %v", err)
+	// 使用常量时间比较，避免通过响应时间推测验证码
+	if subtle.ConstantTimeCompare([]byte(storedCode), []byte(code)) != 1 {
+		return false, s.recordVerifyFailure(ctx, phone, purpose, attempts)
 	}
 
+	// 验证成功后删除验证码和校验次数
+	s.invalidateCode(ctx, phone, purpose)
+
 	return true, nil
 }
 
 // CheckRateLimit 检查限流
-func (s *SMSService) CheckRateLimit(phone, clientIP string) error {
+func (s *SMSService) CheckRateLimit(ctx context.Context, phone, clientIP string) error {
 	// 检查手机号限流（1分钟内只能发送1次）
 	phoneKey := fmt.Sprintf("sms_rate_limit:phone:%s", phone)
-	count, err := cache.IncrementRateLimit(phoneKey, time.Minute)
+	count, err := cache.IncrementRateLimit(ctx, phoneKey, time.Minute)
 	if err != nil {
 		return fmt.Errorf("检查手机号限流失败")
 	}
@@ -120,7 +161,7 @@ func (s *SMSService) CheckRateLimit(phone, clientIP string) error {
 
 	// 检查IP限流（1分钟内最多发送10次）
 	ipKey := fmt.Sprintf("sms_rate_limit:ip:%s", clientIP)
-	count, err = cache.IncrementRateLimit(ipKey, time.Minute)
+	count, err = cache.IncrementRateLimit(ctx, ipKey, time.Minute)
 	if err != nil {
 		return fmt.Errorf("检查IP限流失败")
 	}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"business/database"
+	"business/models"
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrProfileConflict 用户资料已被其他请求修改
+var ErrProfileConflict = errors.New("用户资料已被修改，请刷新后重试")
+
+// ErrNoProfileChanges 修改请求中没有提供任何字段
+var ErrNoProfileChanges = errors.New("没有需要修改的字段")
+
+// birthdayLayout 生日格式
+const birthdayLayout = "2006-01-02"
+
+// ProfileValidationError 用户资料字段校验错误
+type ProfileValidationError struct {
+	Fields map[string]string // 字段名到错误信息
+}
+
+// Error 实现error接口
+func (e *ProfileValidationError) Error() string {
+	return "用户资料校验失败"
+}
+
+// GetProfile 获取用户资料，用户不存在时返回sql.ErrNoRows
+func (s *UserService) GetProfile(ctx context.Context, userID int) (*models.UserProfile, error) {
+	query := `SELECT id, COALESCE(username, ''), COALESCE(phone, ''), nickname, avatar_url, email, gender, birthday, bio, created_at, updated_at
+		FROM users WHERE id = ?`
+
+	var profile models.UserProfile
+	var birthday sql.NullTime
+	err := database.DB.QueryRowContext(ctx, query, userID).Scan(
+		&profile.ID,
+		&profile.Username,
+		&profile.Phone,
+		&profile.Nickname,
+		&profile.AvatarURL,
+		&profile.Email,
+		&profile.Gender,
+		&birthday,
+		&profile.Bio,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if birthday.Valid {
+		profile.Birthday = birthday.Time.Format(birthdayLayout)
+	}
+
+	return &profile, nil
+}
+
+// UpdateProfile 修改用户资料
+// 只有updated_at与数据库一致时才会更新，否则返回ErrProfileConflict；字段校验失败时返回*ProfileValidationError
+func (s *UserService) UpdateProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	if err := validateProfileUpdate(req); err != nil {
+		return nil, err
+	}
+
+	// 只更新请求中提供的字段，updated_at显式更新，保证内容未变化时版本也会前进
+	var sets []string
+	var args []interface{}
+	addString := func(column string, value *string) {
+		if value != nil {
+			sets = append(sets, column+" = ?")
+			args = append(args, strings.TrimSpace(*value))
+		}
+	}
+	addString("nickname", req.Nickname)
+	addString("avatar_url", req.AvatarURL)
+	addString("email", req.Email)
+	addString("gender", req.Gender)
+	addString("bio", req.Bio)
+	if req.Birthday != nil {
+		sets = append(sets, "birthday = ?")
+		if *req.Birthday == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.Birthday)
+		}
+	}
+	if len(sets) == 0 {
+		return nil, ErrNoProfileChanges
+	}
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP(6)")
+
+	query := "UPDATE users SET " + strings.Join(sets, ", ") + " WHERE id = ? AND updated_at = ?"
+	args = append(args, userID, req.UpdatedAt)
+
+	result, err := database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	// 未更新任何行：用户不存在时返回sql.ErrNoRows，否则说明版本已变化
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return profile, ErrProfileConflict
+	}
+
+	return profile, nil
+}
+
+// validateProfileUpdate 校验修改用户资料请求中提供的字段
+func validateProfileUpdate(req *models.UpdateProfileRequest) error {
+	fields := make(map[string]string)
+
+	if req.Nickname != nil && utf8.RuneCountInString(strings.TrimSpace(*req.Nickname)) > 32 {
+		fields["nickname"] = "昵称不能超过32个字符"
+	}
+
+	if req.AvatarURL != nil && *req.AvatarURL != "" {
+		u, err := url.Parse(*req.AvatarURL)
+		switch {
+		case len(*req.AvatarURL) > 512:
+			fields["avatar_url"] = "头像地址不能超过512个字符"
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+			fields["avatar_url"] = "头像地址必须是http或https链接"
+		}
+	}
+
+	if req.Email != nil && *req.Email != "" {
+		address, err := mail.ParseAddress(*req.Email)
+		switch {
+		case len(*req.Email) > 100:
+			fields["email"] = "邮箱不能超过100个字符"
+		case err != nil || address.Address != *req.Email:
+			fields["email"] = "邮箱格式不正确"
+		}
+	}
+
+	if req.Gender != nil {
+		switch *req.Gender {
+		case "", "male", "female", "other":
+		default:
+			fields["gender"] = "性别必须为male、female或other"
+		}
+	}
+
+	if req.Birthday != nil && *req.Birthday != "" {
+		birthday, err := time.Parse(birthdayLayout, *req.Birthday)
+		switch {
+		case err != nil:
+			fields["birthday"] = "生日格式必须为YYYY-MM-DD"
+		case birthday.Year() < 1900 || birthday.After(time.Now()):
+			fields["birthday"] = "生日不在有效范围内"
+		}
+	}
+
+	if req.Bio != nil && utf8.RuneCountInString(strings.TrimSpace(*req.Bio)) > 200 {
+		fields["bio"] = "个人简介不能超过200个字符"
+	}
+
+	if len(fields) > 0 {
+		return &ProfileValidationError{Fields: fields}
+	}
+	return nil
+}
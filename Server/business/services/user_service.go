@@ -4,10 +4,16 @@ import (
 	"business/database"
 	"business/models"
 	"business/utils"
+	"context"
 	"database/sql"
 	"errors"
+
+	"go.opentelemetry.io/otel"
 )
 
+// tracer 服务层链路追踪器，用于记录数据库和缓存之外的耗时操作
+var tracer = otel.Tracer("business/services")
+
 // UserService 用户服务
 type UserService struct{}
 
@@ -17,9 +23,9 @@ func NewUserService() *UserService {
 }
 
 // Register 用户注册
-func (s *UserService) Register(req *models.UserRegisterRequest) (*models.UserResponse, error) {
+func (s *UserService) Register(ctx context.Context, req *models.UserRegisterRequest) (*models.UserResponse, error) {
 	// 检查用户名是否已存在
-	exists, err := s.checkUsernameExists(req.Username)
+	exists, err := s.checkUsernameExists(ctx, req.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +34,9 @@ func (s *UserService) Register(req *models.UserRegisterRequest) (*models.UserRes
 	}
 
 	// 对密码进行哈希
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
 	hashedPassword, err := utils.HashPassword(req.Password)
+	span.End()
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +47,7 @@ func (s *UserService) Register(req *models.UserRegisterRequest) (*models.UserRes
 
 	if req.Phone != "" {
 		// 如果提供了手机号，检查是否已存在
-		phoneExists, err := s.checkPhoneExists(req.Phone)
+		phoneExists, err := s.checkPhoneExists(ctx, req.Phone)
 		if err != nil {
 			return nil, err
 		}
@@ -47,10 +55,10 @@ func (s *UserService) Register(req *models.UserRegisterRequest) (*models.UserRes
 			return nil, errors.New("手机号已存在")
 		}
 		query = `INSERT INTO users (username, password, phone) VALUES (?, ?, ?)`
-		result, err = database.DB.Exec(query, req.Username, hashedPassword, req.Phone)
+		result, err = database.DB.ExecContext(ctx, query, req.Username, hashedPassword, req.Phone)
 	} else {
 		query = `INSERT INTO users (username, password) VALUES (?, ?)`
-		result, err = database.DB.Exec(query, req.Username, hashedPassword)
+		result, err = database.DB.ExecContext(ctx, query, req.Username, hashedPassword)
 	}
 
 	if err != nil {
@@ -64,7 +72,7 @@ func (s *UserService) Register(req *models.UserRegisterRequest) (*models.UserRes
 	}
 
 	// 查询用户信息
-	user, err := s.GetUserByID(int(userID))
+	user, err := s.GetUserByID(ctx, int(userID))
 	if err != nil {
 		return nil, err
 	}
@@ -78,9 +86,9 @@ func (s *UserService) Register(req *models.UserRegisterRequest) (*models.UserRes
 }
 
 // Login 用户登录
-func (s *UserService) Login(req *models.UserLoginRequest) (*models.LoginResponse, error) {
+func (s *UserService) Login(ctx context.Context, req *models.UserLoginRequest) (*models.LoginResponse, error) {
 	// 根据用户名查询用户
-	user, err := s.GetUserByUsername(req.Username)
+	user, err := s.GetUserByUsername(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("用户名或密码错误")
@@ -88,27 +96,100 @@ func (s *UserService) Login(req *models.UserLoginRequest) (*models.LoginResponse
 		return nil, err
 	}
 
-	// 验证密码
-	if !utils.CheckPassword(req.Password, user.Password) {
+	// 验证密码，bcrypt耗时较长，单独记录span
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	valid := utils.CheckPassword(req.Password, user.Password)
+	span.End()
+	if !valid {
 		return nil, errors.New("用户名或密码错误")
 	}
 
+	return s.buildLoginResponse(ctx, user)
+}
+
+// buildLoginResponse 构建登录响应，附带用户的角色和权限供网关写入令牌
+func (s *UserService) buildLoginResponse(ctx context.Context, user *models.User) (*models.LoginResponse, error) {
+	roles, permissions, err := s.GetUserRolesAndPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.LoginResponse{
 		User: models.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Phone:     user.Phone,
-			CreatedAt: user.CreatedAt,
+			ID:          user.ID,
+			Username:    user.Username,
+			Phone:       user.Phone,
+			Roles:       roles,
+			Permissions: permissions,
+			CreatedAt:   user.CreatedAt,
 		},
 	}, nil
 }
 
+// GetUserIdentity 获取用户信息及当前的角色和权限，用户不存在时返回sql.ErrNoRows
+func (s *UserService) GetUserIdentity(ctx context.Context, userID int) (*models.UserResponse, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.buildLoginResponse(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &response.User, nil
+}
+
+// GetUserRolesAndPermissions 获取用户的角色及其拥有的权限
+func (s *UserService) GetUserRolesAndPermissions(ctx context.Context, userID int) ([]string, []string, error) {
+	rows, err := database.DB.QueryContext(ctx, `SELECT role FROM user_roles WHERE user_id = ? ORDER BY role`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, nil, err
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	permRows, err := database.DB.QueryContext(ctx, `
+		SELECT DISTINCT rp.permission FROM role_permissions rp
+		INNER JOIN user_roles ur ON ur.role = rp.role
+		WHERE ur.user_id = ? ORDER BY rp.permission`, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer permRows.Close()
+
+	permissions := []string{}
+	for permRows.Next() {
+		var permission string
+		if err := permRows.Scan(&permission); err != nil {
+			return nil, nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := permRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return roles, permissions, nil
+}
+
 // GetUserByID 根据ID获取用户
-func (s *UserService) GetUserByID(userID int) (*models.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
 	query := `SELECT id, COALESCE(username, '') as username, COALESCE(password, '') as password, COALESCE(phone, '') as phone, created_at, updated_at FROM users WHERE id = ?`
 
 	var user models.User
-	err := database.DB.QueryRow(query, userID).Scan(
+	err := database.DB.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
@@ -125,11 +206,11 @@ func (s *UserService) GetUserByID(userID int) (*models.User, error) {
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `SELECT id, COALESCE(username, '') as username, COALESCE(password, '') as password, COALESCE(phone, '') as phone, created_at, updated_at FROM users WHERE username = ?`
 
 	var user models.User
-	err := database.DB.QueryRow(query, username).Scan(
+	err := database.DB.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
@@ -146,11 +227,11 @@ func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 }
 
 // GetUserByPhone 根据手机号获取用户
-func (s *UserService) GetUserByPhone(phone string) (*models.User, error) {
+func (s *UserService) GetUserByPhone(ctx context.Context, phone string) (*models.User, error) {
 	query := `SELECT id, COALESCE(username, '') as username, COALESCE(password, '') as password, COALESCE(phone, '') as phone, created_at, updated_at FROM users WHERE phone = ?`
 
 	var user models.User
-	err := database.DB.QueryRow(query, phone).Scan(
+	err := database.DB.QueryRowContext(ctx, query, phone).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
@@ -167,11 +248,11 @@ func (s *UserService) GetUserByPhone(phone string) (*models.User, error) {
 }
 
 // checkUsernameExists 检查用户名是否存在
-func (s *UserService) checkUsernameExists(username string) (bool, error) {
+func (s *UserService) checkUsernameExists(ctx context.Context, username string) (bool, error) {
 	query := `SELECT COUNT(*) FROM users WHERE username = ?`
 
 	var count int
-	err := database.DB.QueryRow(query, username).Scan(&count)
+	err := database.DB.QueryRowContext(ctx, query, username).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -180,11 +261,11 @@ func (s *UserService) checkUsernameExists(username string) (bool, error) {
 }
 
 // checkPhoneExists 检查手机号是否存在
-func (s *UserService) checkPhoneExists(phone string) (bool, error) {
+func (s *UserService) checkPhoneExists(ctx context.Context, phone string) (bool, error) {
 	query := `SELECT COUNT(*) FROM users WHERE phone = ?`
 
 	var count int
-	err := database.DB.QueryRow(query, phone).Scan(&count)
+	err := database.DB.QueryRowContext(ctx, query, phone).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -193,9 +274,9 @@ func (s *UserService) checkPhoneExists(phone string) (bool, error) {
 }
 
 // CreateUserByPhone 通过手机号创建用户
-func (s *UserService) CreateUserByPhone(phone string) (*models.User, error) {
+func (s *UserService) CreateUserByPhone(ctx context.Context, phone string) (*models.User, error) {
 	// 检查手机号是否已存在
-	exists, err := s.checkPhoneExists(phone)
+	exists, err := s.checkPhoneExists(ctx, phone)
 	if err != nil {
 		return nil, err
 	}
@@ -205,7 +286,7 @@ func (s *UserService) CreateUserByPhone(phone string) (*models.User, error) {
 
 	// 插入用户数据（只设置手机号，用户名和密码为空）
 	query := `INSERT INTO users (phone) VALUES (?)`
-	result, err := database.DB.Exec(query, phone)
+	result, err := database.DB.ExecContext(ctx, query, phone)
 	if err != nil {
 		return nil, err
 	}
@@ -217,7 +298,7 @@ func (s *UserService) CreateUserByPhone(phone string) (*models.User, error) {
 	}
 
 	// 查询用户信息
-	user, err := s.GetUserByID(int(userID))
+	user, err := s.GetUserByID(ctx, int(userID))
 	if err != nil {
 		return nil, err
 	}
@@ -226,9 +307,9 @@ func (s *UserService) CreateUserByPhone(phone string) (*models.User, error) {
 }
 
 // LoginBySMS 短信验证码登录
-func (s *UserService) LoginBySMS(phone, code string, smsService *SMSService) (*models.LoginResponse, error) {
+func (s *UserService) LoginBySMS(ctx context.Context, phone, code string, smsService *SMSService) (*models.LoginResponse, error) {
 	// 验证短信验证码
-	valid, err := smsService.VerifySMSCode(phone, code)
+	valid, err := smsService.VerifySMSCode(ctx, phone, code, SMSPurposeLogin)
 	if err != nil {
 		return nil, err
 	}
@@ -237,11 +318,11 @@ func (s *UserService) LoginBySMS(phone, code string, smsService *SMSService) (*m
 	}
 
 	// 查找用户，如果不存在则自动创建
-	user, err := s.GetUserByPhone(phone)
+	user, err := s.GetUserByPhone(ctx, phone)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// 用户不存在，自动创建
-			user, err = s.CreateUserByPhone(phone)
+			user, err = s.CreateUserByPhone(ctx, phone)
 			if err != nil {
 				return nil, err
 			}
@@ -250,12 +331,5 @@ func (s *UserService) LoginBySMS(phone, code string, smsService *SMSService) (*m
 		}
 	}
 
-	return &models.LoginResponse{
-		User: models.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Phone:     user.Phone,
-			CreatedAt: user.CreatedAt,
-		},
-	}, nil
+	return s.buildLoginResponse(ctx, user)
 }
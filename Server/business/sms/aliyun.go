@@ -0,0 +1,119 @@
+package sms
+
+import (
+	"business/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
+	dysmsapi20170525 "github.com/alibabacloud-go/dysmsapi-20170525/v3/client"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+// aliyunTimeout 请求阿里云短信服务的最长时间，ctx的截止时间更早时以ctx为准
+const aliyunTimeout = 5 * time.Second
+
+// AliyunSender 阿里云短信发送器
+type AliyunSender struct {
+	client *dysmsapi20170525.Client
+}
+
+// NewAliyunSender 创建阿里云短信发送器
+func NewAliyunSender(cfg *config.SMSConfig) (*AliyunSender, error) {
+	config := &openapi.Config{
+		AccessKeyId:     tea.String(cfg.AccessKeyID),
+		AccessKeySecret: tea.String(cfg.AccessKeySecret),
+	}
+
+	// 访问的域名
+	config.Endpoint = tea.String("dysmsapi.aliyuncs.com")
+
+	client, err := dysmsapi20170525.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("创建阿里云短信客户端失败: %v", err)
+	}
+
+	return &AliyunSender{client: client}, nil
+}
+
+// Send 通过阿里云发送短信
+func (s *AliyunSender) Send(ctx context.Context, msg *Message) error {
+	runtime, err := aliyunRuntime(ctx)
+	if err != nil {
+		return fmt.Errorf("发送短信失败: %v", err)
+	}
+
+	params, err := json.Marshal(msg.Params)
+	if err != nil {
+		return err
+	}
+
+	// 创建短信请求
+	sendSmsRequest := &dysmsapi20170525.SendSmsRequest{
+		PhoneNumbers:  tea.String(msg.Phone),
+		SignName:      tea.String(msg.SignName),
+		TemplateCode:  tea.String(msg.TemplateCode),
+		TemplateParam: tea.String(string(params)),
+	}
+
+	// 发送短信，SDK不接受ctx，ctx结束时不再等待结果，请求本身由runtime的超时时间结束
+	type result struct {
+		response *dysmsapi20170525.SendSmsResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := s.client.SendSmsWithOptions(sendSmsRequest, runtime)
+		done <- result{response, err}
+	}()
+
+	var response *dysmsapi20170525.SendSmsResponse
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("发送短信失败: %v", ctx.Err())
+	case r := <-done:
+		if r.err != nil {
+			return fmt.Errorf("发送短信失败: %v", r.err)
+		}
+		response = r.response
+	}
+
+	// 检查发送结果
+	if response.Body == nil || tea.StringValue(response.Body.Code) != "OK" {
+		message := "响应为空"
+		if response.Body != nil {
+			message = tea.StringValue(response.Body.Message)
+		}
+		return fmt.Errorf("短信发送失败: %s", message)
+	}
+
+	return nil
+}
+
+// aliyunRuntime 根据ctx的截止时间设置连接和读取超时，ctx已结束时返回错误
+// 不自动重试，避免重试时重复发送短信
+func aliyunRuntime(ctx context.Context) (*util.RuntimeOptions, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := aliyunTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	if timeout < time.Millisecond {
+		return nil, context.DeadlineExceeded
+	}
+
+	ms := int(timeout / time.Millisecond)
+	return &util.RuntimeOptions{
+		Autoretry:      tea.Bool(false),
+		ConnectTimeout: tea.Int(ms),
+		ReadTimeout:    tea.Int(ms),
+	}, nil
+}
@@ -0,0 +1,35 @@
+package sms
+
+import (
+	"business/config"
+	"context"
+	"fmt"
+)
+
+// Message 待发送的短信
+type Message struct {
+	Phone        string            `json:"phone"`         // 手机号
+	SignName     string            `json:"sign_name"`     // 短信签名
+	TemplateCode string            `json:"template_code"` // 短信模板代码
+	Params       map[string]string `json:"params"`        // 模板参数
+}
+
+// Sender 短信发送接口
+type Sender interface {
+	// Send 发送短信，返回错误表示发送失败
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewSender 根据配置创建短信发送器
+func NewSender(cfg *config.SMSConfig) (Sender, error) {
+	switch cfg.Provider {
+	case "aliyun":
+		return NewAliyunSender(cfg)
+	case "console":
+		return NewConsoleSender(cfg.FilePath), nil
+	case "http_mock":
+		return NewHTTPMockSender(cfg.MockURL), nil
+	default:
+		return nil, fmt.Errorf("不支持的短信服务提供方: %s", cfg.Provider)
+	}
+}
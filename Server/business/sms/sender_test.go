@@ -0,0 +1,124 @@
+package sms
+
+import (
+	"business/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestMessage 创建包含验证码的测试短信
+func newTestMessage() *Message {
+	return &Message{
+		Phone:        "13800138000",
+		SignName:     "测试签名",
+		TemplateCode: "SMS_TEST",
+		Params:       map[string]string{"code": "123456"},
+	}
+}
+
+func TestHTTPMockSenderSendsCode(t *testing.T) {
+	received := make(chan Message, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode message: %v", err)
+		}
+		received <- msg
+	}))
+	defer server.Close()
+
+	if err := NewHTTPMockSender(server.URL).Send(context.Background(), newTestMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	msg := <-received
+	if msg.Phone != "13800138000" || msg.TemplateCode != "SMS_TEST" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if msg.Params["code"] != "123456" {
+		t.Errorf("code = %q, want %q", msg.Params["code"], "123456")
+	}
+}
+
+func TestHTTPMockSenderRejectsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewHTTPMockSender(server.URL).Send(context.Background(), newTestMessage()); err == nil {
+		t.Fatal("Send succeeded, want error for 500 response")
+	}
+}
+
+func TestConsoleSenderWritesCode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.jsonl")
+	if err := NewConsoleSender(path).Send(context.Background(), newTestMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		Phone  string            `json:"phone"`
+		Params map[string]string `json:"params"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("decode record %q: %v", data, err)
+	}
+	if record.Phone != "13800138000" || record.Params["code"] != "123456" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+}
+
+func TestAliyunSenderCanceledContext(t *testing.T) {
+	sender, err := NewAliyunSender(&config.SMSConfig{AccessKeyID: "id", AccessKeySecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = sender.Send(ctx, newTestMessage())
+	if err == nil {
+		t.Fatal("Send succeeded, want error for canceled context")
+	}
+}
+
+func TestAliyunRuntimeUsesDeadline(t *testing.T) {
+	runtime, err := aliyunRuntime(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *runtime.ReadTimeout; got != int(aliyunTimeout/time.Millisecond) {
+		t.Errorf("ReadTimeout without deadline = %d, want %d", got, aliyunTimeout/time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	runtime, err = aliyunRuntime(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *runtime.ReadTimeout; got <= 0 || got > 1000 {
+		t.Errorf("ReadTimeout with 1s deadline = %d, want (0, 1000]", got)
+	}
+	if *runtime.ConnectTimeout != *runtime.ReadTimeout {
+		t.Errorf("ConnectTimeout = %d, want %d", *runtime.ConnectTimeout, *runtime.ReadTimeout)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := aliyunRuntime(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expired deadline error = %v, want context.DeadlineExceeded", err)
+	}
+}
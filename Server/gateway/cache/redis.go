@@ -8,6 +8,7 @@ import (
 	"gateway/config"
 	"gateway/utils/hkvilog"
 
+	"github.com/go-redis/redis/extra/redisotel/v8"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -22,6 +23,7 @@ func InitRedis(cfg *config.RedisConfig) error {
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
+	RedisClient.AddHook(redisotel.NewTracingHook())
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -37,61 +39,20 @@ func InitRedis(cfg *config.RedisConfig) error {
 }
 
 // CloseRedis 关闭Redis连接
-func CloseRedis() {
-	if RedisClient != nil {
-		if err := RedisClient.Close(); err != nil {
-			hkvilog.Errorf("关闭Redis连接失败: %v", err)
-		} else {
-			hkvilog.Info("Redis连接已关闭")
-		}
+func CloseRedis() error {
+	if RedisClient == nil {
+		return nil
 	}
-}
-
-// StoreRefreshToken 存储刷新令牌到Redis
-func StoreRefreshToken(userID int, refreshToken string, expireTime time.Duration) error {
-	ctx := context.Background()
-	key := fmt.Sprintf("refresh_token:%d", userID)
-
-	err := RedisClient.Set(ctx, key, refreshToken, expireTime).Err()
-	if err != nil {
-		return fmt.Errorf("存储刷新令牌失败: %v", err)
+	if err := RedisClient.Close(); err != nil {
+		hkvilog.Errorf("关闭Redis连接失败: %v", err)
+		return err
 	}
-
-	return nil
-}
-
-// GetRefreshToken 从Redis获取刷新令牌
-func GetRefreshToken(userID int) (string, error) {
-	ctx := context.Background()
-	key := fmt.Sprintf("refresh_token:%d", userID)
-
-	token, err := RedisClient.Get(ctx, key).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return "", fmt.Errorf("刷新令牌不存在")
-		}
-		return "", fmt.Errorf("获取刷新令牌失败: %v", err)
-	}
-
-	return token, nil
-}
-
-// DeleteRefreshToken 从Redis删除刷新令牌
-func DeleteRefreshToken(userID int) error {
-	ctx := context.Background()
-	key := fmt.Sprintf("refresh_token:%d", userID)
-
-	err := RedisClient.Del(ctx, key).Err()
-	if err != nil {
-		return fmt.Errorf("删除刷新令牌失败: %v", err)
-	}
-
+	hkvilog.Info("Redis连接已关闭")
 	return nil
 }
 
 // BlacklistToken 将令牌加入黑名单
-func BlacklistToken(tokenID string, expireTime time.Duration) error {
-	ctx := context.Background()
+func BlacklistToken(ctx context.Context, tokenID string, expireTime time.Duration) error {
 	key := fmt.Sprintf("blacklist:%s", tokenID)
 
 	err := RedisClient.Set(ctx, key, "1", expireTime).Err()
@@ -103,8 +64,7 @@ func BlacklistToken(tokenID string, expireTime time.Duration) error {
 }
 
 // IsTokenBlacklisted 检查令牌是否在黑名单中
-func IsTokenBlacklisted(tokenID string) (bool, error) {
-	ctx := context.Background()
+func IsTokenBlacklisted(ctx context.Context, tokenID string) (bool, error) {
 	key := fmt.Sprintf("blacklist:%s", tokenID)
 
 	_, err := RedisClient.Get(ctx, key).Result()
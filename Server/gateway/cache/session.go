@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Session 登录会话，每次登录（每台设备）对应一个会话
+// 会话同时也是刷新令牌族：同一会话内轮换出的刷新令牌属于同一族，撤销会话即撤销整个令牌族
+type Session struct {
+	ID             string    `json:"id"`               // 会话ID
+	UserID         int       `json:"user_id"`          // 用户ID
+	Username       string    `json:"username"`         // 用户名
+	DeviceName     string    `json:"device_name"`      // 设备名称
+	UserAgent      string    `json:"user_agent"`       // 客户端User-Agent
+	IP             string    `json:"ip"`               // 客户端IP
+	RefreshTokenID string    `json:"refresh_token_id"` // 当前有效的刷新令牌ID
+	CreatedAt      time.Time `json:"created_at"`       // 创建时间
+	LastUsedAt     time.Time `json:"last_used_at"`     // 最近使用时间
+}
+
+// 会话键格式，网关和业务服务共用同一个Redis库中的会话（见API接口文档“共享Redis键”）
+// 两个服务各自定义了相同的格式，修改时必须同时修改，启动时通过RegisterSessionSchema互相校验
+const (
+	sessionKeyFormat      = "session:%s"       // 会话，值为会话JSON
+	userSessionsKeyFormat = "user_sessions:%d" // 用户的会话ID集合
+)
+
+// sessionKey 会话键
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf(sessionKeyFormat, sessionID)
+}
+
+// userSessionsKey 用户会话集合键
+func userSessionsKey(userID int) string {
+	return fmt.Sprintf(userSessionsKeyFormat, userID)
+}
+
+// SaveSession 保存会话，并将其加入用户的会话集合
+func SaveSession(ctx context.Context, session *Session, expireTime time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %v", err)
+	}
+
+	pipe := RedisClient.TxPipeline()
+	pipe.Set(ctx, sessionKey(session.ID), data, expireTime)
+	pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+	pipe.Expire(ctx, userSessionsKey(session.UserID), expireTime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("保存会话失败: %v", err)
+	}
+
+	return nil
+}
+
+// GetSession 获取会话
+func GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	data, err := RedisClient.Get(ctx, sessionKey(sessionID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("会话不存在")
+		}
+		return nil, fmt.Errorf("获取会话失败: %v", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("解析会话失败: %v", err)
+	}
+
+	return &session, nil
+}
+
+// RotateSessionRefreshToken 原子地轮换会话中的刷新令牌
+// 仅当会话当前的刷新令牌ID等于expectedTokenID时才会更新，返回false表示令牌已被轮换过（即令牌重用）
+func RotateSessionRefreshToken(ctx context.Context, sessionID, expectedTokenID, newTokenID string, expireTime time.Duration) (bool, error) {
+	key := sessionKey(sessionID)
+	rotated := false
+
+	err := RedisClient.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			return err
+		}
+
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return err
+		}
+		if session.RefreshTokenID != expectedTokenID {
+			return nil
+		}
+
+		session.RefreshTokenID = newTokenID
+		session.LastUsedAt = time.Now()
+		newData, err := json.Marshal(&session)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, newData, expireTime)
+			pipe.Expire(ctx, userSessionsKey(session.UserID), expireTime)
+			return nil
+		})
+		if err == nil {
+			rotated = true
+		}
+		return err
+	}, key)
+
+	if err != nil {
+		if err == redis.TxFailedErr {
+			// 并发轮换：其他请求已抢先使用同一刷新令牌
+			return false, nil
+		}
+		if err == redis.Nil {
+			return false, fmt.Errorf("会话不存在")
+		}
+		return false, fmt.Errorf("轮换刷新令牌失败: %v", err)
+	}
+
+	return rotated, nil
+}
+
+// SessionExists 检查会话是否仍然有效
+func SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	count, err := RedisClient.Exists(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("检查会话失败: %v", err)
+	}
+
+	return count > 0, nil
+}
+
+// ListUserSessions 获取用户的所有有效会话，按最近使用时间倒序排列
+func ListUserSessions(ctx context.Context, userID int) ([]*Session, error) {
+	sessionIDs, err := RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取会话列表失败: %v", err)
+	}
+	if len(sessionIDs) == 0 {
+		return []*Session{}, nil
+	}
+
+	keys := make([]string, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		keys[i] = sessionKey(sessionID)
+	}
+
+	values, err := RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取会话列表失败: %v", err)
+	}
+
+	sessions := make([]*Session, 0, len(values))
+	var expired []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// 会话已过期，从集合中清理
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+
+		var session Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if len(expired) > 0 {
+		RedisClient.SRem(ctx, userSessionsKey(userID), expired...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+// DeleteSession 删除会话
+func DeleteSession(ctx context.Context, userID int, sessionID string) error {
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionID))
+	pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("删除会话失败: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteUserSessions 删除用户的所有会话
+func DeleteUserSessions(ctx context.Context, userID int) error {
+	sessionIDs, err := RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("获取会话列表失败: %v", err)
+	}
+
+	pipe := RedisClient.TxPipeline()
+	for _, sessionID := range sessionIDs {
+		pipe.Del(ctx, sessionKey(sessionID))
+	}
+	pipe.Del(ctx, userSessionsKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("删除会话失败: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gateway/utils/hkvilog"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 会话键格式标记：每个服务把自己使用的会话键格式写入共享Redis，并检查另一个服务写入的标记
+// 能读到另一个服务的标记说明两者连接的是同一个Redis库，格式一致说明会话可以互相识别
+const (
+	sessionSchemaService = "gateway"  // 本服务名称
+	sessionSchemaPeer    = "business" // 共用会话的另一个服务
+	sessionSchemaTTL     = time.Minute
+	sessionSchemaRefresh = 20 * time.Second
+)
+
+// ErrSessionSchemaMissing 共享Redis中没有另一个服务的会话键格式标记
+// 另一个服务尚未启动，或者两个服务连接的不是同一个Redis库
+var ErrSessionSchemaMissing = errors.New("共享Redis中没有另一个服务的会话键格式标记")
+
+// sessionSchema 本服务使用的会话键格式
+var sessionSchema = sessionKeyFormat + " " + userSessionsKeyFormat
+
+// sessionSchemaKey 服务的会话键格式标记键
+func sessionSchemaKey(service string) string {
+	return fmt.Sprintf("session_schema:%s", service)
+}
+
+// RegisterSessionSchema 写入本服务的会话键格式标记，并校验另一个服务的标记
+// 另一个服务的标记不存在时返回ErrSessionSchemaMissing，格式不一致时返回错误
+func RegisterSessionSchema(ctx context.Context) error {
+	if err := RedisClient.Set(ctx, sessionSchemaKey(sessionSchemaService), sessionSchema, sessionSchemaTTL).Err(); err != nil {
+		return fmt.Errorf("写入会话键格式标记失败: %v", err)
+	}
+
+	peerSchema, err := RedisClient.Get(ctx, sessionSchemaKey(sessionSchemaPeer)).Result()
+	if err == redis.Nil {
+		return ErrSessionSchemaMissing
+	}
+	if err != nil {
+		return fmt.Errorf("读取会话键格式标记失败: %v", err)
+	}
+	if peerSchema != sessionSchema {
+		return fmt.Errorf("会话键格式与%s不一致: 本服务为 %q，%s为 %q", sessionSchemaPeer, sessionSchema, sessionSchemaPeer, peerSchema)
+	}
+	return nil
+}
+
+// WatchSessionSchema 定期刷新本服务的会话键格式标记并校验另一个服务的标记，出现问题时记录日志，直到stop关闭
+func WatchSessionSchema(stop <-chan struct{}) {
+	ticker := time.NewTicker(sessionSchemaRefresh)
+	defer ticker.Stop()
+
+	var lastErr string
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := RegisterSessionSchema(ctx)
+		cancel()
+
+		// 只在状态变化时记录，避免每次刷新都输出相同的日志
+		var current string
+		if err != nil {
+			current = err.Error()
+		}
+		if current == lastErr {
+			continue
+		}
+		if err != nil {
+			hkvilog.Errorf("会话键格式校验失败，%s无法识别本服务的会话: %v", sessionSchemaPeer, err)
+		} else {
+			hkvilog.Infof("会话键格式校验通过，与%s共用同一个Redis库", sessionSchemaPeer)
+		}
+		lastErr = current
+	}
+}
@@ -1,60 +1,239 @@
 package config
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 网关配置结构体
 type Config struct {
-	Server      ServerConfig      `json:"server"`       // 服务器配置
-	JWT         JWTConfig         `json:"jwt"`          // JWT配置
-	Redis       RedisConfig       `json:"redis"`        // Redis配置
-	BusinessAPI BusinessAPIConfig `json:"business_api"` // 业务服务API配置
+	Server      ServerConfig       `json:"server"`       // 服务器配置
+	JWT         JWTConfig          `json:"jwt"`          // JWT配置
+	Redis       RedisConfig        `json:"redis"`        // Redis配置
+	BusinessAPI BusinessAPIConfig  `json:"business_api"` // 业务服务API配置
+	Upstreams   []UpstreamConfig   `json:"upstreams"`    // 上游服务列表
+	Transport   TransportConfig    `json:"transport"`    // 上游连接池配置
+	Routes      []RouteConfig      `json:"routes"`       // 代理路由表
+	AccessRules []AccessRuleConfig `json:"access_rules"` // 代理接口访问控制规则
+	RateLimit   RateLimitConfig    `json:"rate_limit"`   // 限流配置
+	CORS        CORSConfig         `json:"cors"`         // 跨域配置
+	Log         LogConfig          `json:"log"`          // 日志配置
+	Tracing     TracingConfig      `json:"tracing"`      // 链路追踪配置
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port string `json:"port"` // 服务器端口
-	Mode string `json:"mode"` // 运行模式
+	Port            string `json:"port"`             // 服务器端口
+	Mode            string `json:"mode"`             // 运行模式
+	ShutdownDelay   int    `json:"shutdown_delay"`   // 收到退出信号后就绪检查返回503并等待的时间（秒），让负载均衡器先摘除实例
+	ShutdownTimeout int    `json:"shutdown_timeout"` // 等待正在处理的请求完成的最长时间（秒），超时后强制关闭
+	MetricsPort     string `json:"metrics_port"`     // Prometheus指标端口，与服务端口分开监听，为空时不提供指标
 }
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	AccessSecretKey  string `json:"access_secret_key"`  // Access Token密钥
-	RefreshSecretKey string `json:"refresh_secret_key"` // Refresh Token密钥
-	AccessExpire     int    `json:"access_expire"`      // Access Token过期时间（秒）
-	RefreshExpire    int    `json:"refresh_expire"`     // Refresh Token过期时间（秒）
+	AccessSecretKey  string         `json:"access_secret_key" secret:"true"`  // Access Token密钥（HS256时使用）
+	RefreshSecretKey string         `json:"refresh_secret_key" secret:"true"` // Refresh Token密钥
+	AccessExpire     int            `json:"access_expire"`                    // Access Token过期时间（秒）
+	RefreshExpire    int            `json:"refresh_expire"`                   // Refresh Token过期时间（秒）
+	Algorithm        string         `json:"algorithm"`                        // Access Token签名算法：HS256（默认）、RS256、ES256、EdDSA等
+	SigningKeyID     string         `json:"signing_key_id"`                   // 当前用于签名的密钥ID，为空时使用第一把密钥
+	Keys             []JWTKeyConfig `json:"keys"`                             // 非对称密钥列表，轮换时保留旧密钥用于验证
+}
+
+// JWTKeyConfig JWT非对称密钥配置
+type JWTKeyConfig struct {
+	ID             string `json:"kid"`              // 密钥ID，写入令牌头的kid
+	Algorithm      string `json:"algorithm"`        // 签名算法，为空时使用JWTConfig.Algorithm
+	PrivateKeyFile string `json:"private_key_file"` // PEM格式私钥文件，仅签名密钥需要
+	PublicKeyFile  string `json:"public_key_file"`  // PEM格式公钥文件，为空时从私钥推导
 }
 
 // RedisConfig Redis配置
 type RedisConfig struct {
-	Host     string `json:"host"`     // Redis主机
-	Port     string `json:"port"`     // Redis端口
-	Password string `json:"password"` // Redis密码
-	DB       int    `json:"db"`       // Redis数据库编号
+	Host     string `json:"host"`                   // Redis主机
+	Port     string `json:"port"`                   // Redis端口
+	Password string `json:"password" secret:"true"` // Redis密码
+	DB       int    `json:"db"`                     // Redis数据库编号
 }
 
 // BusinessAPIConfig 业务服务API配置
 type BusinessAPIConfig struct {
-	BaseURL string `json:"base_url"` // 业务服务基础URL
-	Timeout int    `json:"timeout"`  // 请求超时时间（秒）
+	BaseURL        string               `json:"base_url"`        // 业务服务基础URL
+	Instances      []string             `json:"instances"`       // 业务服务实例URL列表，配置后替代base_url
+	Timeout        int                  `json:"timeout"`         // 请求超时时间（秒）
+	LoadBalance    LoadBalanceConfig    `json:"load_balance"`    // 负载均衡配置
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"` // 熔断配置
+	Retry          RetryConfig          `json:"retry"`           // 重试配置
+}
+
+// LoadBalanceConfig 负载均衡配置
+type LoadBalanceConfig struct {
+	Strategy     string             `json:"strategy"`      // 负载均衡策略：round_robin（默认）、least_conn、consistent_hash
+	HealthCheck  HealthCheckConfig  `json:"health_check"`  // 主动健康检查配置
+	PassiveCheck PassiveCheckConfig `json:"passive_check"` // 被动健康检查配置
+}
+
+// HealthCheckConfig 主动健康检查配置
+type HealthCheckConfig struct {
+	Path               string `json:"path"`                // 健康检查路径，默认 /api/health/ready
+	Interval           int    `json:"interval"`            // 检查间隔（秒），为0时不进行主动检查
+	Timeout            int    `json:"timeout"`             // 检查超时时间（秒）
+	HealthyThreshold   int    `json:"healthy_threshold"`   // 连续成功多少次后恢复实例
+	UnhealthyThreshold int    `json:"unhealthy_threshold"` // 连续失败多少次后摘除实例
+}
+
+// PassiveCheckConfig 被动健康检查配置
+type PassiveCheckConfig struct {
+	MaxFailures   int `json:"max_failures"`   // 连续出现5xx或连接错误多少次后摘除实例，为0时不摘除
+	EjectDuration int `json:"eject_duration"` // 摘除时长（秒），到期后自动重新接入
+}
+
+// CircuitBreakerConfig 熔断配置
+type CircuitBreakerConfig struct {
+	FailureThreshold int `json:"failure_threshold"`  // 连续失败多少次后熔断，为0时不熔断
+	OpenTimeout      int `json:"open_timeout"`       // 熔断持续时间（秒），到期后进入半开状态
+	HalfOpenRequests int `json:"half_open_requests"` // 半开状态下允许的试探请求数，全部成功后恢复
+}
+
+// RetryConfig 重试配置
+type RetryConfig struct {
+	MaxRetries int `json:"max_retries"` // 最大重试次数，为0时不重试
+	BaseDelay  int `json:"base_delay"`  // 退避基础时间（毫秒）
+	MaxDelay   int `json:"max_delay"`   // 退避最大时间（毫秒）
+}
+
+// TransportConfig 上游连接池配置，所有上游共用一个连接池
+type TransportConfig struct {
+	MaxIdleConns          int  `json:"max_idle_conns"`          // 最大空闲连接数
+	MaxIdleConnsPerHost   int  `json:"max_idle_conns_per_host"` // 每个实例的最大空闲连接数
+	MaxConnsPerHost       int  `json:"max_conns_per_host"`      // 每个实例的最大连接数，为0时不限制
+	IdleConnTimeout       int  `json:"idle_conn_timeout"`       // 空闲连接超时时间（秒）
+	DialTimeout           int  `json:"dial_timeout"`            // 建立连接超时时间（秒）
+	KeepAlive             int  `json:"keep_alive"`              // TCP keep-alive间隔（秒）
+	TLSHandshakeTimeout   int  `json:"tls_handshake_timeout"`   // TLS握手超时时间（秒）
+	ResponseHeaderTimeout int  `json:"response_header_timeout"` // 等待响应头超时时间（秒），为0时只受请求超时限制
+	DisableKeepAlives     bool `json:"disable_keep_alives"`     // 禁用长连接
+}
+
+// UpstreamConfig 上游服务配置
+type UpstreamConfig struct {
+	Name           string               `json:"name"`            // 上游名称，供路由引用
+	BaseURL        string               `json:"base_url"`        // 上游服务基础URL
+	Instances      []string             `json:"instances"`       // 上游实例URL列表，配置后替代base_url
+	Timeout        int                  `json:"timeout"`         // 默认请求超时时间（秒）
+	LoadBalance    LoadBalanceConfig    `json:"load_balance"`    // 负载均衡配置
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"` // 熔断配置
+	Retry          RetryConfig          `json:"retry"`           // 重试配置
+}
+
+// RouteConfig 代理路由配置
+// 请求按最长前缀匹配路由，再转发到对应的上游服务
+type RouteConfig struct {
+	Name          string   `json:"name"`           // 路由名称
+	Prefix        string   `json:"prefix"`         // 匹配的路径前缀，如 /api/business
+	Methods       []string `json:"methods"`        // 匹配的请求方法，为空表示全部方法
+	Hosts         []string `json:"hosts"`          // 匹配的Host，为空表示全部Host
+	Upstream      string   `json:"upstream"`       // 目标上游名称
+	StripPrefix   bool     `json:"strip_prefix"`   // 转发前去掉匹配的前缀
+	RewritePrefix string   `json:"rewrite_prefix"` // 将匹配的前缀替换为该值，优先于strip_prefix
+	Auth          bool     `json:"auth"`           // 是否需要认证
+	Timeout       int      `json:"timeout"`        // 请求超时时间（秒），为0时使用上游的超时时间
+}
+
+// AccessRuleConfig 按路径前缀的访问控制规则
+type AccessRuleConfig struct {
+	Prefix      string   `json:"prefix"`      // 路径前缀，如 /api/business/admin
+	Methods     []string `json:"methods"`     // 适用的请求方法，为空表示全部方法
+	Roles       []string `json:"roles"`       // 允许访问的角色（满足任一即可）
+	Permissions []string `json:"permissions"` // 需要的权限（必须全部满足）
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	Login RateLimitRule `json:"login"` // 登录相关接口限流
+	SMS   RateLimitRule `json:"sms"`   // 短信接口限流
+}
+
+// RateLimitRule 限流规则
+type RateLimitRule struct {
+	MaxRequests int `json:"max_requests"` // 时间窗口内最多请求次数
+	Window      int `json:"window"`       // 时间窗口（秒）
 }
 
-// LoadConfig 加载应用配置
-func LoadConfig() *Config {
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowOrigins []string `json:"allow_origins"` // 允许的来源，为空时允许所有来源，"*" 表示全部
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string        `json:"level"`  // 日志级别：debug、info（默认）、warn、error
+	Format string        `json:"format"` // 日志格式：text（默认）、json
+	File   LogFileConfig `json:"file"`   // 文件日志配置
+}
+
+// LogFileConfig 文件日志配置
+type LogFileConfig struct {
+	Path       string `json:"path"`        // 日志文件路径，为空时只输出到标准输出
+	ErrorPath  string `json:"error_path"`  // 错误日志文件路径，ERROR级别的日志同时写入该文件
+	Stdout     bool   `json:"stdout"`      // 写入文件的同时输出到标准输出
+	MaxSize    int    `json:"max_size"`    // 单个文件最大大小（MB），为0时不按大小轮转
+	Rotate     string `json:"rotate"`      // 按时间轮转：hourly、daily，为空时不按时间轮转
+	MaxBackups int    `json:"max_backups"` // 保留的轮转文件数，为0时全部保留
+	Compress   bool   `json:"compress"`    // 使用gzip压缩轮转后的文件
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`      // 是否启用链路追踪，未启用时仍透传traceparent
+	ServiceName string  `json:"service_name"` // 上报的服务名称
+	Exporter    string  `json:"exporter"`     // 导出方式：otlp（默认）、stdout、file
+	Endpoint    string  `json:"endpoint"`     // OTLP/HTTP接收地址，如 localhost:4318
+	Insecure    bool    `json:"insecure"`     // OTLP使用HTTP而非HTTPS
+	FilePath    string  `json:"file_path"`    // exporter为file时写入的文件
+	SampleRatio float64 `json:"sample_ratio"` // 采样比例（0-1），上游已采样的请求始终采样
+}
+
+// LoadConfig 加载并校验应用配置
+// 未找到配置文件时使用默认配置，配置文件无法读取、解析失败、引用无法解析或校验不通过时返回错误
+func LoadConfig() (*Config, error) {
+	config, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	// 解析 ${ENV} 和 file: 引用
+	if err := resolveReferences(config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// load 依次应用默认配置、配置文件和环境变量
+func load() (*Config, error) {
 	// 默认配置
 	config := &Config{
 		Server: ServerConfig{
-			Port: "8080",
-			Mode: "debug",
+			Port:            "8080",
+			Mode:            "debug",
+			ShutdownDelay:   5,
+			ShutdownTimeout: 15,
+			MetricsPort:     "9090",
 		},
 		JWT: JWTConfig{
 			AccessSecretKey:  "your-access-secret-key",
 			RefreshSecretKey: "your-refresh-secret-key",
 			AccessExpire:     900,   // 15分钟
 			RefreshExpire:    86400, // 24小时
+			Algorithm:        "HS256",
 		},
 		Redis: RedisConfig{
 			Host:     "localhost",
@@ -65,34 +244,69 @@ func LoadConfig() *Config {
 		BusinessAPI: BusinessAPIConfig{
 			BaseURL: "http://localhost:8081",
 			Timeout: 30,
+			LoadBalance: LoadBalanceConfig{
+				Strategy: "round_robin",
+				HealthCheck: HealthCheckConfig{
+					Path:               "/api/health/ready",
+					Interval:           10,
+					Timeout:            2,
+					HealthyThreshold:   2,
+					UnhealthyThreshold: 3,
+				},
+				PassiveCheck: PassiveCheckConfig{
+					MaxFailures:   3,
+					EjectDuration: 30,
+				},
+			},
+			CircuitBreaker: CircuitBreakerConfig{
+				FailureThreshold: 5,
+				OpenTimeout:      30,
+				HalfOpenRequests: 1,
+			},
+			Retry: RetryConfig{
+				MaxRetries: 2,
+				BaseDelay:  50,
+				MaxDelay:   1000,
+			},
+		},
+		Transport: TransportConfig{
+			MaxIdleConns:        200,
+			MaxIdleConnsPerHost: 50,
+			IdleConnTimeout:     90,
+			DialTimeout:         5,
+			KeepAlive:           30,
+			TLSHandshakeTimeout: 5,
+		},
+		RateLimit: RateLimitConfig{
+			Login: RateLimitRule{
+				MaxRequests: 10, // 5分钟内最多10次
+				Window:      300,
+			},
+			SMS: RateLimitRule{
+				MaxRequests: 5, // 1分钟内最多5次
+				Window:      60,
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "gateway",
+			Exporter:    "otlp",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
 		},
 	}
 
 	// 尝试从配置文件加载
-	if err := loadFromFile(config); err != nil {
-		// 如果配置文件不存在或读取失败，使用默认配置
-	}
+	err := loadFromFile(config)
 
 	// 从环境变量覆盖配置
 	loadFromEnv(config)
 
-	return config
-}
-
-// loadFromFile 从配置文件加载配置
-func loadFromFile(config *Config) error {
-	configFile := getConfigFile()
-	if configFile == "" {
-		return nil
-	}
-
-	file, err := os.Open(configFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	return json.NewDecoder(file).Decode(config)
+	return config, err
 }
 
 // loadFromEnv 从环境变量加载配置
@@ -104,6 +318,9 @@ func loadFromEnv(config *Config) {
 	if mode := os.Getenv("GATEWAY_MODE"); mode != "" {
 		config.Server.Mode = mode
 	}
+	if port, ok := os.LookupEnv("GATEWAY_METRICS_PORT"); ok {
+		config.Server.MetricsPort = port
+	}
 
 	// JWT配置
 	if accessKey := os.Getenv("JWT_ACCESS_SECRET_KEY"); accessKey != "" {
@@ -122,6 +339,12 @@ func loadFromEnv(config *Config) {
 			config.JWT.RefreshExpire = expire
 		}
 	}
+	if algorithm := os.Getenv("JWT_ALGORITHM"); algorithm != "" {
+		config.JWT.Algorithm = algorithm
+	}
+	if signingKeyID := os.Getenv("JWT_SIGNING_KEY_ID"); signingKeyID != "" {
+		config.JWT.SigningKeyID = signingKeyID
+	}
 
 	// Redis配置
 	if host := os.Getenv("REDIS_HOST"); host != "" {
@@ -139,32 +362,46 @@ func loadFromEnv(config *Config) {
 		}
 	}
 
+	// 日志配置
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		config.Log.Level = level
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.Log.Format = format
+	}
+	if file := os.Getenv("LOG_FILE"); file != "" {
+		config.Log.File.Path = file
+	}
+
+	// 链路追踪配置
+	if enabled := os.Getenv("TRACING_ENABLED"); enabled != "" {
+		config.Tracing.Enabled = enabled == "true" || enabled == "1"
+	}
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		config.Tracing.Exporter = exporter
+	}
+	if endpoint := os.Getenv("TRACING_ENDPOINT"); endpoint != "" {
+		config.Tracing.Endpoint = endpoint
+	}
+	if ratioStr := os.Getenv("TRACING_SAMPLE_RATIO"); ratioStr != "" {
+		if ratio, err := strconv.ParseFloat(ratioStr, 64); err == nil {
+			config.Tracing.SampleRatio = ratio
+		}
+	}
+
 	// 业务服务API配置
 	if baseURL := os.Getenv("BUSINESS_API_BASE_URL"); baseURL != "" {
 		config.BusinessAPI.BaseURL = baseURL
 	}
+	if instances := os.Getenv("BUSINESS_API_INSTANCES"); instances != "" {
+		config.BusinessAPI.Instances = strings.Split(instances, ",")
+	}
+	if strategy := os.Getenv("BUSINESS_API_LB_STRATEGY"); strategy != "" {
+		config.BusinessAPI.LoadBalance.Strategy = strategy
+	}
 	if timeoutStr := os.Getenv("BUSINESS_API_TIMEOUT"); timeoutStr != "" {
 		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
 			config.BusinessAPI.Timeout = timeout
 		}
 	}
 }
-
-// getConfigFile 获取配置文件路径
-func getConfigFile() string {
-	// 按优先级查找配置文件
-	configFiles := []string{
-		"gateway-config.json",
-		"config/gateway-config.json",
-		"./gateway-config.json",
-		"./config/gateway-config.json",
-	}
-
-	for _, file := range configFiles {
-		if _, err := os.Stat(file); err == nil {
-			return file
-		}
-	}
-
-	return ""
-}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Diff 比较两份配置，返回变化的字段列表，字段使用配置文件中的名称表示
+// 标记为secret的敏感字段只提示发生变化，不输出具体值
+func Diff(old, new *Config) []string {
+	var changes []string
+	diffValue("", reflect.ValueOf(*old), reflect.ValueOf(*new), false, &changes)
+	return changes
+}
+
+// diffValue 递归比较结构体字段
+func diffValue(path string, old, new reflect.Value, sensitive bool, changes *[]string) {
+	if old.Kind() == reflect.Struct {
+		for i := 0; i < old.NumField(); i++ {
+			field := old.Type().Field(i)
+			diffValue(fieldPath(path, field), old.Field(i), new.Field(i), sensitive || isSecretField(field), changes)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return
+	}
+	if sensitive {
+		*changes = append(*changes, fmt.Sprintf("%s: 已修改", path))
+		return
+	}
+	*changes = append(*changes, fmt.Sprintf("%s: %v -> %v", path, formatValue(old), formatValue(new)))
+}
+
+// formatValue 格式化字段值，复杂类型输出为紧凑形式
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return "[]"
+		}
+		return fmt.Sprintf("%+v", v.Interface())
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	default:
+		return fmt.Sprintf("%v", v.Interface())
+	}
+}
+
+// RequiresRestart 检查变化的字段是否需要重启服务才能生效
+func RequiresRestart(change string) bool {
+	for _, prefix := range []string{"server.", "redis.", "log.file.", "tracing."} {
+		if strings.HasPrefix(change, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MinSecretLength HMAC密钥的最小长度
+const MinSecretLength = 32
+
+// ValidationError 配置校验错误，包含发现的全部问题
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 返回全部问题，每行一个
+func (e *ValidationError) Error() string {
+	return "配置校验失败:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator 收集校验问题
+type validator struct {
+	problems []string
+}
+
+// addf 记录一个问题
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// Validate 校验配置，一次返回全部问题
+// debug模式下允许使用示例密钥，其他模式下必须配置真实密钥
+func (c *Config) Validate() error {
+	v := &validator{}
+	debug := c.Server.Mode == "debug"
+
+	// 服务器配置
+	v.checkPort("server.port", c.Server.Port)
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		v.addf("server.mode 必须为 debug、release 或 test，当前为 %q", c.Server.Mode)
+	}
+	if c.Server.MetricsPort != "" {
+		v.checkPort("server.metrics_port", c.Server.MetricsPort)
+		if c.Server.MetricsPort == c.Server.Port {
+			v.addf("server.metrics_port 不能与 server.port 相同")
+		}
+	}
+	if c.Server.ShutdownDelay < 0 || c.Server.ShutdownTimeout < 0 {
+		v.addf("server.shutdown_delay 和 server.shutdown_timeout 不能为负数")
+	}
+
+	// JWT配置
+	algorithm := c.JWT.Algorithm
+	if algorithm == "" || strings.HasPrefix(algorithm, "HS") {
+		if algorithm != "" && algorithm != "HS256" {
+			v.addf("jwt.algorithm 不支持 %s", algorithm)
+		}
+		v.checkSecret("jwt.access_secret_key", c.JWT.AccessSecretKey, debug)
+	} else {
+		v.checkJWTKeys(&c.JWT)
+	}
+	v.checkSecret("jwt.refresh_secret_key", c.JWT.RefreshSecretKey, debug)
+	if c.JWT.AccessExpire <= 0 {
+		v.addf("jwt.access_expire 必须大于0")
+	}
+	if c.JWT.RefreshExpire <= 0 {
+		v.addf("jwt.refresh_expire 必须大于0")
+	} else if c.JWT.RefreshExpire < c.JWT.AccessExpire {
+		v.addf("jwt.refresh_expire 不能小于 jwt.access_expire")
+	}
+
+	// Redis配置
+	if c.Redis.Host == "" {
+		v.addf("redis.host 不能为空")
+	}
+	v.checkPort("redis.port", c.Redis.Port)
+	if c.Redis.DB < 0 {
+		v.addf("redis.db 不能为负数")
+	}
+
+	// 上游配置
+	upstreams := map[string]bool{"business": true}
+	v.checkUpstream("business_api", c.BusinessAPI.BaseURL, c.BusinessAPI.Instances, c.BusinessAPI.Timeout, c.BusinessAPI.LoadBalance)
+	for i, upstream := range c.Upstreams {
+		path := fmt.Sprintf("upstreams[%d]", i)
+		if upstream.Name == "" {
+			v.addf("%s.name 不能为空", path)
+		} else if upstreams[upstream.Name] {
+			v.addf("%s.name 重复: %s", path, upstream.Name)
+		}
+		upstreams[upstream.Name] = true
+		v.checkUpstream(path, upstream.BaseURL, upstream.Instances, upstream.Timeout, upstream.LoadBalance)
+	}
+
+	// 路由和访问控制
+	for i, route := range c.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		if !strings.HasPrefix(route.Prefix, "/") {
+			v.addf("%s.prefix 必须以/开头", path)
+		}
+		if !upstreams[route.Upstream] {
+			v.addf("%s.upstream 引用的上游 %q 不存在", path, route.Upstream)
+		}
+		if route.Timeout < 0 {
+			v.addf("%s.timeout 不能为负数", path)
+		}
+	}
+	for i, rule := range c.AccessRules {
+		if !strings.HasPrefix(rule.Prefix, "/") {
+			v.addf("access_rules[%d].prefix 必须以/开头", i)
+		}
+	}
+
+	// 限流配置
+	v.checkRateLimit("rate_limit.login", c.RateLimit.Login)
+	v.checkRateLimit("rate_limit.sms", c.RateLimit.SMS)
+
+	// 跨域配置
+	for i, origin := range c.CORS.AllowOrigins {
+		if origin != "*" && !isHTTPURL(origin) {
+			v.addf("cors.allow_origins[%d] 不是有效的来源: %s", i, origin)
+		}
+	}
+
+	// 日志配置
+	switch strings.ToLower(c.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		v.addf("log.level 必须为 debug、info、warn 或 error，当前为 %q", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "", "text", "json":
+	default:
+		v.addf("log.format 必须为 text 或 json，当前为 %q", c.Log.Format)
+	}
+	switch c.Log.File.Rotate {
+	case "", "hourly", "daily":
+	default:
+		v.addf("log.file.rotate 必须为 hourly 或 daily，当前为 %q", c.Log.File.Rotate)
+	}
+	if c.Log.File.ErrorPath != "" && c.Log.File.Path == "" {
+		v.addf("log.file.error_path 需要同时配置 log.file.path")
+	}
+	if c.Log.File.MaxSize < 0 || c.Log.File.MaxBackups < 0 {
+		v.addf("log.file.max_size 和 log.file.max_backups 不能为负数")
+	}
+
+	// 链路追踪配置
+	v.checkTracing(&c.Tracing)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// checkTracing 校验链路追踪配置，未启用时不校验
+func (v *validator) checkTracing(cfg *TracingConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	switch cfg.Exporter {
+	case "otlp":
+		if cfg.Endpoint == "" {
+			v.addf("tracing.endpoint 不能为空")
+		}
+	case "stdout":
+	case "file":
+		if cfg.FilePath == "" {
+			v.addf("tracing.exporter 为 file 时必须配置 tracing.file_path")
+		}
+	default:
+		v.addf("tracing.exporter 必须为 otlp、stdout 或 file，当前为 %q", cfg.Exporter)
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio 必须在0到1之间，当前为 %v", cfg.SampleRatio)
+	}
+}
+
+// checkPort 校验端口号
+func (v *validator) checkPort(path, port string) {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		v.addf("%s 不是有效的端口: %q", path, port)
+	}
+}
+
+// checkSecret 校验HMAC密钥，debug模式下允许使用示例密钥
+func (v *validator) checkSecret(path, secret string, debug bool) {
+	switch {
+	case secret == "":
+		v.addf("%s 不能为空", path)
+	case isPlaceholder(secret):
+		if !debug {
+			v.addf("%s 仍为示例值，非debug模式下必须修改", path)
+		}
+	case len(secret) < MinSecretLength:
+		v.addf("%s 长度不能少于%d个字符", path, MinSecretLength)
+	}
+}
+
+// checkJWTKeys 校验非对称密钥配置
+func (v *validator) checkJWTKeys(cfg *JWTConfig) {
+	if len(cfg.Keys) == 0 {
+		v.addf("jwt.algorithm 为 %s 时必须配置 jwt.keys", cfg.Algorithm)
+		return
+	}
+
+	ids := make(map[string]bool, len(cfg.Keys))
+	for i, key := range cfg.Keys {
+		path := fmt.Sprintf("jwt.keys[%d]", i)
+		if key.ID == "" {
+			v.addf("%s.kid 不能为空", path)
+		} else if ids[key.ID] {
+			v.addf("%s.kid 重复: %s", path, key.ID)
+		}
+		ids[key.ID] = true
+
+		if key.PrivateKeyFile == "" && key.PublicKeyFile == "" {
+			v.addf("%s 至少需要配置 private_key_file 或 public_key_file", path)
+		}
+		for _, file := range []string{key.PrivateKeyFile, key.PublicKeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				v.addf("%s 密钥文件不可读: %s", path, file)
+			}
+		}
+	}
+
+	if cfg.SigningKeyID != "" && !ids[cfg.SigningKeyID] {
+		v.addf("jwt.signing_key_id 引用的密钥 %q 不存在", cfg.SigningKeyID)
+	}
+}
+
+// checkUpstream 校验上游地址和负载均衡配置
+func (v *validator) checkUpstream(path, baseURL string, instances []string, timeout int, lb LoadBalanceConfig) {
+	if len(instances) == 0 {
+		if baseURL == "" {
+			v.addf("%s 必须配置 base_url 或 instances", path)
+		} else if !isHTTPURL(baseURL) {
+			v.addf("%s.base_url 不是有效的URL: %s", path, baseURL)
+		}
+	}
+	for i, instance := range instances {
+		if !isHTTPURL(strings.TrimSpace(instance)) {
+			v.addf("%s.instances[%d] 不是有效的URL: %s", path, i, instance)
+		}
+	}
+
+	if timeout <= 0 {
+		v.addf("%s.timeout 必须大于0", path)
+	}
+
+	switch lb.Strategy {
+	case "", "round_robin", "least_conn", "consistent_hash":
+	default:
+		v.addf("%s.load_balance.strategy 无效: %s", path, lb.Strategy)
+	}
+	if lb.HealthCheck.Interval < 0 || lb.HealthCheck.Timeout < 0 {
+		v.addf("%s.load_balance.health_check 的间隔和超时时间不能为负数", path)
+	}
+}
+
+// checkRateLimit 校验限流规则
+func (v *validator) checkRateLimit(path string, rule RateLimitRule) {
+	if rule.MaxRequests <= 0 {
+		v.addf("%s.max_requests 必须大于0", path)
+	}
+	if rule.Window <= 0 {
+		v.addf("%s.window 必须大于0", path)
+	}
+}
+
+// isHTTPURL 检查是否为有效的http/https地址
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isPlaceholder 检查是否为示例配置中的占位值
+func isPlaceholder(value string) bool {
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "your-") {
+		return true
+	}
+	switch lower {
+	case "secret", "changeme", "change-me", "password":
+		return true
+	}
+	return false
+}
@@ -3,34 +3,48 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"gateway/cache"
 	"gateway/config"
+	"gateway/middleware"
+	"gateway/upstream"
 	"gateway/utils"
 	"gateway/utils/hkvilog"
 
 	"github.com/gin-gonic/gin"
 )
 
+// errUserNotFound 刷新令牌时业务服务中已不存在该用户
+var errUserNotFound = errors.New("用户不存在")
+
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	cfg *config.Config
+	cfg          *config.Config
+	accessKeys   *utils.KeySet  // 访问令牌密钥集合
+	refreshKeys  *utils.KeySet  // 刷新令牌密钥集合（仅网关自身验证，始终使用HS256）
+	businessPool *upstream.Pool // 业务服务实例池
 }
 
 // NewAuthHandler 创建认证处理器实例
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, accessKeys *utils.KeySet, businessPool *upstream.Pool) *AuthHandler {
 	return &AuthHandler{
-		cfg: cfg,
+		cfg:          cfg,
+		accessKeys:   accessKeys,
+		refreshKeys:  utils.NewHMACKeySet(cfg.JWT.RefreshSecretKey),
+		businessPool: businessPool,
 	}
 }
 
 // LoginRequest 登录请求结构
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"device_name,omitempty"`
 }
 
 // RegisterRequest 注册请求结构
@@ -42,13 +56,27 @@ type RegisterRequest struct {
 
 // SMSRequest 短信请求结构
 type SMSRequest struct {
-	Phone string `json:"phone" binding:"required"`
+	Phone   string `json:"phone" binding:"required"`
+	Purpose string `json:"purpose,omitempty"`
 }
 
 // SMSLoginRequest 短信登录请求结构
 type SMSLoginRequest struct {
+	Phone      string `json:"phone" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+// PasswordResetRequest 申请重置密码请求结构
+type PasswordResetRequest struct {
 	Phone string `json:"phone" binding:"required"`
-	Code  string `json:"code" binding:"required"`
+}
+
+// PasswordResetConfirmRequest 确认重置密码请求结构
+type PasswordResetConfirmRequest struct {
+	Phone       string `json:"phone" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 // RefreshTokenRequest 刷新令牌请求结构
@@ -76,13 +104,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// 转发请求到业务服务
-	resp, err := h.forwardToBusinessService("POST", "/api/auth/login", req)
+	resp, err := h.forwardToBusinessService(c, "POST", "/api/auth/login", req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "登录服务暂不可用",
 		})
 		return
 	}
+	defer resp.Body.Close()
 
 	// 如果业务服务返回成功，生成双token
 	if resp.StatusCode == http.StatusOK {
@@ -111,36 +140,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			return
 		}
 
-		userID := int(user["id"].(float64))
-		username := user["username"].(string)
-
-		// 生成双token
-		accessToken, refreshToken, err := utils.GenerateTokenPair(
-			userID,
-			username,
-			h.cfg.JWT.AccessSecretKey,
-			h.cfg.JWT.RefreshSecretKey,
-			h.cfg.JWT.AccessExpire,
-			h.cfg.JWT.RefreshExpire,
-		)
+		// 为本次登录创建会话并生成双token
+		tokens, session, err := h.createSession(c, subjectFromUser(user), req.DeviceName)
 		if err != nil {
+			hkvilog.Errorf("创建会话失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "令牌生成失败",
 			})
 			return
 		}
 
-		// 存储刷新令牌到Redis
-		if err := cache.StoreRefreshToken(userID, refreshToken, time.Duration(h.cfg.JWT.RefreshExpire)*time.Second); err != nil {
-			hkvilog.Errorf("存储刷新令牌失败: %v", err)
-		}
-
 		c.JSON(http.StatusOK, gin.H{
 			"message": "登录成功",
 			"data": gin.H{
-				"access_token":  accessToken,
-				"refresh_token": refreshToken,
+				"access_token":  tokens.AccessToken,
+				"refresh_token": tokens.RefreshToken,
 				"expires_in":    h.cfg.JWT.AccessExpire,
+				"session_id":    session.ID,
 				"user":          user,
 			},
 		})
@@ -163,9 +179,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// 转发请求到业务服务
-	resp, err := h.forwardToBusinessService("POST", "/api/auth/register", req)
+	resp, err := h.forwardToBusinessService(c, "POST", "/api/auth/register", req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "注册服务暂不可用",
 		})
 		return
@@ -187,9 +203,9 @@ func (h *AuthHandler) SendSMS(c *gin.Context) {
 	}
 
 	// 转发请求到业务服务
-	resp, err := h.forwardToBusinessService("POST", "/api/sms/send", req)
+	resp, err := h.forwardToBusinessService(c, "POST", "/api/sms/send", req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "短信服务暂不可用",
 		})
 		return
@@ -211,13 +227,14 @@ func (h *AuthHandler) SMSLogin(c *gin.Context) {
 	}
 
 	// 转发请求到业务服务
-	resp, err := h.forwardToBusinessService("POST", "/api/sms/login", req)
+	resp, err := h.forwardToBusinessService(c, "POST", "/api/sms/login", req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "短信登录服务暂不可用",
 		})
 		return
 	}
+	defer resp.Body.Close()
 
 	// 如果业务服务返回成功，生成双token
 	if resp.StatusCode == http.StatusOK {
@@ -246,39 +263,23 @@ func (h *AuthHandler) SMSLogin(c *gin.Context) {
 			return
 		}
 
-		userID := int(user["id"].(float64))
-		username := ""
-		if user["username"] != nil {
-			username = user["username"].(string)
-		}
-
-		// 生成双token
-		accessToken, refreshToken, err := utils.GenerateTokenPair(
-			userID,
-			username,
-			h.cfg.JWT.AccessSecretKey,
-			h.cfg.JWT.RefreshSecretKey,
-			h.cfg.JWT.AccessExpire,
-			h.cfg.JWT.RefreshExpire,
-		)
+		// 为本次登录创建会话并生成双token
+		tokens, session, err := h.createSession(c, subjectFromUser(user), req.DeviceName)
 		if err != nil {
+			hkvilog.Errorf("创建会话失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "令牌生成失败",
 			})
 			return
 		}
 
-		// 存储刷新令牌到Redis
-		if err := cache.StoreRefreshToken(userID, refreshToken, time.Duration(h.cfg.JWT.RefreshExpire)*time.Second); err != nil {
-			hkvilog.Errorf("存储刷新令牌失败: %v", err)
-		}
-
 		c.JSON(http.StatusOK, gin.H{
 			"message": "登录成功",
 			"data": gin.H{
-				"access_token":  accessToken,
-				"refresh_token": refreshToken,
+				"access_token":  tokens.AccessToken,
+				"refresh_token": tokens.RefreshToken,
 				"expires_in":    h.cfg.JWT.AccessExpire,
+				"session_id":    session.ID,
 				"user":          user,
 			},
 		})
@@ -289,6 +290,54 @@ func (h *AuthHandler) SMSLogin(c *gin.Context) {
 	h.forwardResponse(c, resp)
 }
 
+// RequestPasswordReset 申请重置密码处理器
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 转发请求到业务服务
+	resp, err := h.forwardToBusinessService(c, "POST", "/api/auth/password/reset/request", req)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "重置密码服务暂不可用",
+		})
+		return
+	}
+
+	// 转发业务服务的响应
+	h.forwardResponse(c, resp)
+}
+
+// ConfirmPasswordReset 确认重置密码处理器，业务服务重置成功后会注销该用户的所有会话
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 转发请求到业务服务
+	resp, err := h.forwardToBusinessService(c, "POST", "/api/auth/password/reset/confirm", req)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "重置密码服务暂不可用",
+		})
+		return
+	}
+
+	// 转发业务服务的响应
+	h.forwardResponse(c, resp)
+}
+
 // RefreshToken 刷新令牌处理器
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
@@ -301,7 +350,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	// 验证刷新令牌
-	claims, err := utils.ValidateRefreshToken(req.RefreshToken, h.cfg.JWT.RefreshSecretKey)
+	claims, err := utils.ValidateRefreshToken(req.RefreshToken, h.refreshKeys)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "无效的刷新令牌",
@@ -309,21 +358,45 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// 检查Redis中的刷新令牌
-	storedToken, err := cache.GetRefreshToken(claims.UserID)
-	if err != nil || storedToken != req.RefreshToken {
+	// 检查刷新令牌所属的会话（令牌族）
+	session, err := cache.GetSession(c.Request.Context(), claims.SessionID)
+	if err != nil || session.UserID != claims.UserID {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已失效",
+		})
+		return
+	}
+
+	// 已被轮换过的刷新令牌再次出现，视为令牌被盗用，撤销整个令牌族
+	if session.RefreshTokenID != claims.ID {
+		h.revokeTokenFamily(c, session, claims)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已失效",
+		})
+		return
+	}
+
+	// 从业务服务重新获取角色和权限，角色变更后刷新的令牌立即生效
+	subject, err := h.fetchSubject(c, claims)
+	if errors.Is(err, errUserNotFound) {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "刷新令牌已失效",
 		})
 		return
 	}
+	if err != nil {
+		hkvilog.Errorf("获取用户角色失败: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "刷新服务暂不可用",
+		})
+		return
+	}
 
-	// 生成新的双token
-	newAccessToken, newRefreshToken, err := utils.GenerateTokenPair(
-		claims.UserID,
-		claims.Username,
-		h.cfg.JWT.AccessSecretKey,
-		h.cfg.JWT.RefreshSecretKey,
+	// 生成新的双token，只轮换当前会话
+	tokens, err := utils.GenerateTokenPair(
+		subject,
+		h.accessKeys,
+		h.refreshKeys,
 		h.cfg.JWT.AccessExpire,
 		h.cfg.JWT.RefreshExpire,
 	)
@@ -334,16 +407,28 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// 更新Redis中的刷新令牌
-	if err := cache.StoreRefreshToken(claims.UserID, newRefreshToken, time.Duration(h.cfg.JWT.RefreshExpire)*time.Second); err != nil {
-		hkvilog.Errorf("更新刷新令牌失败: %v", err)
+	// 原子地更新会话中的刷新令牌，防止同一令牌被并发使用
+	rotated, err := cache.RotateSessionRefreshToken(c.Request.Context(), session.ID, claims.ID, tokens.RefreshClaims.ID, time.Duration(h.cfg.JWT.RefreshExpire)*time.Second)
+	if err != nil {
+		hkvilog.Errorf("更新会话失败: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已失效",
+		})
+		return
+	}
+	if !rotated {
+		h.revokeTokenFamily(c, session, claims)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已失效",
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "令牌刷新成功",
 		"data": gin.H{
-			"access_token":  newAccessToken,
-			"refresh_token": newRefreshToken,
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 			"expires_in":    h.cfg.JWT.AccessExpire,
 		},
 	})
@@ -360,54 +445,187 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	// tokenID, exists := c.Get("token_id")
-	// if !exists {
-	//	c.JSON(http.StatusUnauthorized, gin.H{
-	//		"error": "令牌信息不存在",
-	//	})
-	//	return
-	// }
+	tokenID, exists := c.Get("token_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "令牌信息不存在",
+		})
+		return
+	}
 
-	// 删除Redis中的刷新令牌
-	if err := cache.DeleteRefreshToken(userID.(int)); err != nil {
-		hkvilog.Errorf("删除刷新令牌失败: %v", err)
+	// 删除当前会话，使其刷新令牌失效
+	if sessionID, exists := c.Get("session_id"); exists {
+		if err := cache.DeleteSession(c.Request.Context(), userID.(int), sessionID.(string)); err != nil {
+			hkvilog.Errorf("删除会话失败: %v", err)
+		}
 	}
 
-	// 将访问令牌加入黑名单 (暂时注释)
-	// if err := cache.BlacklistToken(tokenID.(string), time.Duration(h.cfg.JWT.AccessExpire)*time.Second); err != nil {
-	//	hkvilog.Errorf("添加令牌黑名单失败: %v", err)
-	// }
+	// 将访问令牌加入黑名单，有效期为令牌的剩余生命周期，无法获取过期时间时使用访问令牌的完整有效期
+	remaining := time.Duration(h.cfg.JWT.AccessExpire) * time.Second
+	if value, ok := c.Get("token_expires_at"); ok {
+		if expiresAt, ok := value.(time.Time); ok && !expiresAt.IsZero() {
+			remaining = time.Until(expiresAt)
+		}
+	}
+	if remaining > 0 {
+		if err := cache.BlacklistToken(c.Request.Context(), tokenID.(string), remaining); err != nil {
+			hkvilog.Errorf("添加令牌黑名单失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "退出失败，请稍后再试",
+			})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "退出成功",
 	})
 }
 
+// revokeTokenFamily 检测到刷新令牌重用时撤销整个令牌族，并记录安全事件
+// 撤销会话后，由该会话签发的所有访问令牌和刷新令牌都会在认证时被拒绝
+func (h *AuthHandler) revokeTokenFamily(c *gin.Context, session *cache.Session, claims *utils.Claims) {
+	hkvilog.Warnf("安全事件: 检测到刷新令牌重用，撤销令牌族 user_id=%d session_id=%s token_id=%s ip=%s user_agent=%q",
+		session.UserID,
+		session.ID,
+		claims.ID,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+
+	if err := cache.DeleteSession(c.Request.Context(), session.UserID, session.ID); err != nil {
+		hkvilog.Errorf("撤销令牌族失败: %v", err)
+	}
+}
+
+// subjectFromUser 从业务服务返回的用户信息中提取令牌主体（用户ID、用户名、角色和权限）
+func subjectFromUser(user map[string]interface{}) *utils.TokenSubject {
+	subject := &utils.TokenSubject{}
+	if id, ok := user["id"].(float64); ok {
+		subject.UserID = int(id)
+	}
+	if username, ok := user["username"].(string); ok {
+		subject.Username = username
+	}
+	subject.Roles = toStringSlice(user["roles"])
+	subject.Permissions = toStringSlice(user["permissions"])
+	return subject
+}
+
+// fetchSubject 从业务服务获取用户当前的角色和权限，构建刷新后令牌的主体
+func (h *AuthHandler) fetchSubject(c *gin.Context, claims *utils.Claims) (*utils.TokenSubject, error) {
+	req, err := h.businessPool.NewRequest(c.Request.Context(), http.MethodGet, "/api/users/me/identity", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-User-ID", fmt.Sprintf("%d", claims.UserID))
+	req.Header.Set(middleware.RequestIDHeader, c.GetString(middleware.RequestIDKey))
+
+	resp, err := h.businessPool.Client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errUserNotFound
+	default:
+		return nil, fmt.Errorf("业务服务返回状态码 %d", resp.StatusCode)
+	}
+
+	var businessResp BusinessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&businessResp); err != nil {
+		return nil, err
+	}
+	userData, _ := businessResp.Data.(map[string]interface{})
+	user, ok := userData["user"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("用户信息格式错误")
+	}
+
+	subject := subjectFromUser(user)
+	subject.UserID = claims.UserID
+	subject.SessionID = claims.SessionID
+	return subject, nil
+}
+
+// toStringSlice 将JSON数组转换为字符串切片
+func toStringSlice(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+// createSession 创建登录会话并签发绑定该会话的双token
+func (h *AuthHandler) createSession(c *gin.Context, subject *utils.TokenSubject, deviceName string) (*utils.TokenPair, *cache.Session, error) {
+	sessionID, err := utils.GenerateTokenID()
+	if err != nil {
+		return nil, nil, err
+	}
+	subject.SessionID = sessionID
+
+	tokens, err := utils.GenerateTokenPair(
+		subject,
+		h.accessKeys,
+		h.refreshKeys,
+		h.cfg.JWT.AccessExpire,
+		h.cfg.JWT.RefreshExpire,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	session := &cache.Session{
+		ID:             sessionID,
+		UserID:         subject.UserID,
+		Username:       subject.Username,
+		DeviceName:     deviceName,
+		UserAgent:      c.Request.UserAgent(),
+		IP:             c.ClientIP(),
+		RefreshTokenID: tokens.RefreshClaims.ID,
+		CreatedAt:      now,
+		LastUsedAt:     now,
+	}
+
+	// 存储会话到Redis
+	if err := cache.SaveSession(c.Request.Context(), session, time.Duration(h.cfg.JWT.RefreshExpire)*time.Second); err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, session, nil
+}
+
 // forwardToBusinessService 转发请求到业务服务
-func (h *AuthHandler) forwardToBusinessService(method, path string, data interface{}) (*http.Response, error) {
+func (h *AuthHandler) forwardToBusinessService(c *gin.Context, method, path string, data interface{}) (*http.Response, error) {
 	// 序列化请求数据
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
-	// 构建请求URL
-	url := h.cfg.BusinessAPI.BaseURL + path
-
-	// 创建HTTP请求
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(jsonData))
+	// 创建HTTP请求，实例选择、熔断和重试由业务服务实例池完成
+	req, err := h.businessPool.NewRequest(c.Request.Context(), method, path, bytes.NewReader(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.RequestIDHeader, c.GetString(middleware.RequestIDKey))
 
 	// 发送请求
-	client := &http.Client{
-		Timeout: time.Duration(h.cfg.BusinessAPI.Timeout) * time.Second,
-	}
-
-	return client.Do(req)
+	return h.businessPool.Client().Do(req)
 }
 
 // forwardResponse 转发响应
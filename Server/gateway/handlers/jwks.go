@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开访问令牌的验证公钥（JSON Web Key Set）
+// 业务服务和第三方只需公钥即可验证网关签发的访问令牌
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"keys": h.accessKeys.JWKS(),
+	})
+}
@@ -1,72 +1,273 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"gateway/config"
+	"gateway/middleware"
+	"gateway/upstream"
 	"gateway/utils/hkvilog"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ProxyToBusiness 代理请求到业务服务
-func ProxyToBusiness(c *gin.Context) {
-	cfg := config.LoadConfig()
+// proxyRouteKey 上下文中保存匹配路由的键
+const proxyRouteKey = "proxy_route"
 
-	// 解析业务服务URL
-	target, err := url.Parse(cfg.BusinessAPI.BaseURL)
-	if err != nil {
-		hkvilog.Errorf("解析业务服务URL失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "业务服务配置错误",
-		})
-		return
-	}
+// reservedBusinessPaths 业务服务中由网关单独提供的接口前缀，代理路由不转发
+// 网关在这些接口上施加了登录限流和短信限流，经代理转发会绕过限流
+var reservedBusinessPaths = []string{"/api/auth", "/api/sms"}
 
-	// 创建反向代理
-	proxy := httputil.NewSingleHostReverseProxy(target)
+// ProxyRoute 代理路由
+type ProxyRoute struct {
+	config.RouteConfig
+	pool    *upstream.Pool
+	proxy   *httputil.ReverseProxy
+	timeout time.Duration
+}
 
-	// 修改请求
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalDirector(req)
+// ProxyHandler 代理处理器，按配置的路由表将请求转发到各个上游服务
+type ProxyHandler struct {
+	routes []*ProxyRoute // 按前缀长度倒序排列
+}
+
+// NewProxyHandler 根据配置创建代理处理器
+func NewProxyHandler(cfg *config.Config, upstreams *upstream.Registry) (*ProxyHandler, error) {
+	routeConfigs := cfg.Routes
+	if len(routeConfigs) == 0 {
+		// 未配置路由表时保持原有行为：/api/business/* 转发到业务服务的 /api/*
+		routeConfigs = []config.RouteConfig{{
+			Name:          "business",
+			Prefix:        "/api/business",
+			Upstream:      upstream.DefaultName,
+			RewritePrefix: "/api",
+			Auth:          true,
+		}}
+	}
+
+	h := &ProxyHandler{}
+	for _, routeCfg := range routeConfigs {
+		if !strings.HasPrefix(routeCfg.Prefix, "/") {
+			return nil, fmt.Errorf("路由 %s 的前缀必须以/开头", routeCfg.Name)
+		}
 
-		// 修改请求路径，去掉/api/business前缀
-		req.URL.Path = strings.Replace(req.URL.Path, "/api/business", "/api", 1)
+		pool, ok := upstreams.Get(routeCfg.Upstream)
+		if !ok {
+			return nil, fmt.Errorf("路由 %s 引用的上游 %s 不存在", routeCfg.Name, routeCfg.Upstream)
+		}
 
-		// 添加用户信息到请求头
-		if userID, exists := c.Get("user_id"); exists {
-			req.Header.Set("X-User-ID", fmt.Sprintf("%d", userID.(int)))
+		route := &ProxyRoute{
+			RouteConfig: routeCfg,
+			pool:        pool,
+			timeout:     pool.Timeout,
 		}
-		if username, exists := c.Get("username"); exists {
-			req.Header.Set("X-Username", username.(string))
+		if routeCfg.Timeout > 0 {
+			route.timeout = time.Duration(routeCfg.Timeout) * time.Second
 		}
+		route.proxy = newReverseProxy(route)
+
+		h.routes = append(h.routes, route)
+	}
+
+	sort.SliceStable(h.routes, func(i, j int) bool {
+		return len(h.routes[i].Prefix) > len(h.routes[j].Prefix)
+	})
+
+	return h, nil
+}
+
+// newReverseProxy 为路由创建反向代理，由上游实例池完成实例选择、熔断和重试
+func newReverseProxy(route *ProxyRoute) *httputil.ReverseProxy {
+	proxy := &httputil.ReverseProxy{
+		Transport: route.pool,
+	}
 
-		// 设置目标主机
-		req.Host = target.Host
+	// 修改请求：只按路由规则改写路径，实例地址由实例池在发送时填充
+	proxy.Director = func(req *http.Request) {
+		req.URL.Scheme = "http"
+		req.URL.Host = route.pool.Name
+		req.URL.Path = route.rewritePath(req.URL.Path)
+		req.URL.RawPath = ""
 
-		hkvilog.Infof("代理请求到业务服务: %s %s", req.Method, req.URL.String())
+		hkvilog.With("request_id", req.Header.Get(middleware.RequestIDHeader)).Infof("代理请求到上游 %s: %s %s", route.pool.Name, req.Method, req.URL.Path)
 	}
 
-	// 修改响应
+	// 网关已设置请求ID响应头，移除上游回显的同名头避免重复
 	proxy.ModifyResponse = func(resp *http.Response) error {
-		// 可以在这里修改响应
+		resp.Header.Del(middleware.RequestIDHeader)
 		return nil
 	}
 
-	// 错误处理
+	// 错误处理：熔断或无可用实例返回503，超时返回504，其余返回502
 	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
-		hkvilog.Errorf("代理请求失败: %v", err)
-		w.WriteHeader(http.StatusBadGateway)
+		hkvilog.With("request_id", req.Header.Get(middleware.RequestIDHeader)).Errorf("代理请求到上游 %s 失败: %v", route.pool.Name, err)
+
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, upstream.ErrCircuitOpen), errors.Is(err, upstream.ErrNoAvailableInstance):
+			status = http.StatusServiceUnavailable
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
 		io.WriteString(w, `{"error":"业务服务暂不可用"}`)
 	}
 
-	// 执行代理
-	proxy.ServeHTTP(c.Writer, c.Request)
+	return proxy
+}
+
+// rewritePath 按路由规则改写请求路径
+func (r *ProxyRoute) rewritePath(path string) string {
+	if r.RewritePrefix != "" {
+		return r.RewritePrefix + strings.TrimPrefix(path, r.Prefix)
+	}
+	if r.StripPrefix {
+		path = strings.TrimPrefix(path, r.Prefix)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	return path
+}
+
+// reserved 检查请求改写后的路径是否为网关单独提供的业务服务接口
+func (r *ProxyRoute) reserved(req *http.Request) bool {
+	if r.Upstream != upstream.DefaultName {
+		return false
+	}
+
+	// 按上游看到的路径判断，避免通过多余的斜杠或 ./.. 绕过
+	target := path.Clean("/" + r.rewritePath(req.URL.Path))
+	for _, prefix := range reservedBusinessPaths {
+		if target == prefix || strings.HasPrefix(target, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 检查请求是否匹配路由
+func (r *ProxyRoute) matches(req *http.Request) bool {
+	path := req.URL.Path
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return false
+	}
+
+	if len(r.Methods) > 0 && !containsFold(r.Methods, req.Method) {
+		return false
+	}
+
+	if len(r.Hosts) > 0 {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !containsFold(r.Hosts, host) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchRoute 匹配代理路由，未匹配或请求网关单独提供的业务服务接口时返回404
+func (h *ProxyHandler) MatchRoute(c *gin.Context) {
+	for _, route := range h.routes {
+		if route.matches(c.Request) {
+			if route.reserved(c.Request) {
+				break
+			}
+			c.Set(proxyRouteKey, route)
+			c.Set(middleware.RouteNameKey, route.Name)
+			trace.SpanFromContext(c.Request.Context()).SetName("proxy " + route.Name)
+			c.Next()
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "接口不存在",
+	})
+	c.Abort()
+}
+
+// RequireAuth 对需要认证的路由执行认证中间件
+func (h *ProxyHandler) RequireAuth(authMiddleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route, ok := c.Get(proxyRouteKey); ok && route.(*ProxyRoute).Auth {
+			authMiddleware(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Serve 将请求转发到匹配路由的上游服务
+func (h *ProxyHandler) Serve(c *gin.Context) {
+	value, ok := c.Get(proxyRouteKey)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "接口不存在",
+		})
+		return
+	}
+	route := value.(*ProxyRoute)
+
+	// 添加用户信息到请求头，不信任客户端自带的用户头
+	req := c.Request
+	req.Header.Del("X-User-ID")
+	req.Header.Del("X-Username")
+	req.Header.Del("X-User-Roles")
+	if userID, exists := c.Get("user_id"); exists {
+		req.Header.Set("X-User-ID", fmt.Sprintf("%d", userID.(int)))
+	}
+	if username, exists := c.Get("username"); exists {
+		req.Header.Set("X-Username", username.(string))
+	}
+	if roles := c.GetStringSlice("roles"); len(roles) > 0 {
+		req.Header.Set("X-User-Roles", strings.Join(roles, ","))
+	}
+
+	// 转发请求ID，便于跨服务关联日志
+	req.Header.Set(middleware.RequestIDHeader, c.GetString(middleware.RequestIDKey))
+
+	// 一致性哈希按用户ID（未登录时按客户端IP）分配实例
+	hashKey := c.ClientIP()
+	if userID, exists := c.Get("user_id"); exists {
+		hashKey = fmt.Sprintf("%d", userID.(int))
+	}
+	ctx := upstream.WithHashKey(req.Context(), hashKey)
+
+	// 设置路由超时时间
+	if route.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, route.timeout)
+		defer cancel()
+	}
+
+	route.proxy.ServeHTTP(c.Writer, req.WithContext(ctx))
+}
+
+// containsFold 不区分大小写地检查切片是否包含指定值
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,170 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gateway/config"
+	"gateway/handlers"
+	"gateway/upstream"
+	"gateway/utils/hkvilog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestConfig 启动测试上游，并把配置文件指向它
+func newTestConfig(tb testing.TB) {
+	tb.Helper()
+
+	gin.SetMode(gin.ReleaseMode)
+	hkvilog.SetLevel(hkvilog.ERROR)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"message":"ok"}`)
+	}))
+	tb.Cleanup(server.Close)
+
+	data, err := json.Marshal(map[string]interface{}{
+		"server":       map[string]interface{}{"mode": "debug"},
+		"business_api": map[string]interface{}{"base_url": server.URL},
+		"routes": []map[string]interface{}{{
+			"name":           "business",
+			"prefix":         "/api/business",
+			"upstream":       upstream.DefaultName,
+			"rewrite_prefix": "/api",
+		}},
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	file := filepath.Join(tb.TempDir(), "gateway-config.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	config.SetConfigFile(file)
+}
+
+// newProxyEngine 按路由配置创建只包含代理的引擎，上游实例池和反向代理都在这里构建
+func newProxyEngine(tb testing.TB, cfg *config.Config) (*gin.Engine, *upstream.Registry) {
+	registry, err := upstream.NewRegistry(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	proxyHandler, err := handlers.NewProxyHandler(cfg, registry)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	r := gin.New()
+	r.NoRoute(proxyHandler.MatchRoute, proxyHandler.Serve)
+	return r, registry
+}
+
+// serveProxy 通过代理转发一次请求并检查响应
+func serveProxy(b *testing.B, r *gin.Engine) {
+	req := httptest.NewRequest(http.MethodGet, "/api/business/users/me", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		b.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestProxyRejectsGatewayOnlyPaths(t *testing.T) {
+	newTestConfig(t)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, registry := newProxyEngine(t, cfg)
+	defer registry.Stop()
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/business/users/me", http.StatusOK},
+		{"/api/business/smsx", http.StatusOK},
+		{"/api/business/sms/send", http.StatusNotFound},
+		{"/api/business/sms", http.StatusNotFound},
+		{"/api/business/auth/password/reset/request", http.StatusNotFound},
+		{"/api/business/auth/login", http.StatusNotFound},
+		{"/api/business//sms/send", http.StatusNotFound},
+		{"/api/business/users/../sms/send", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.URL.Path = tt.path
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("POST %s: status %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
+
+// BenchmarkProxyPerRequest 每次请求都重新加载配置并构建实例池、连接池和反向代理（改造前的做法）
+func BenchmarkProxyPerRequest(b *testing.B) {
+	newTestConfig(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			b.Fatal(err)
+		}
+		r, registry := newProxyEngine(b, cfg)
+		serveProxy(b, r)
+		registry.Stop()
+	}
+}
+
+// BenchmarkProxyShared 配置、实例池、连接池和反向代理只构建一次，所有请求复用
+func BenchmarkProxyShared(b *testing.B) {
+	newTestConfig(b)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+	r, registry := newProxyEngine(b, cfg)
+	b.Cleanup(registry.Stop)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serveProxy(b, r)
+	}
+}
+
+// BenchmarkProxySharedParallel 并发请求复用同一个代理
+func BenchmarkProxySharedParallel(b *testing.B) {
+	newTestConfig(b)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+	r, registry := newProxyEngine(b, cfg)
+	b.Cleanup(registry.Stop)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req := httptest.NewRequest(http.MethodGet, "/api/business/users/me", nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				b.Errorf("unexpected status %d", rec.Code)
+				return
+			}
+		}
+	})
+}
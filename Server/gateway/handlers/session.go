@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gateway/cache"
+	"gateway/utils/hkvilog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions 获取当前用户的登录会话列表
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	currentSessionID := c.GetString("session_id")
+
+	sessions, err := cache.ListUserSessions(c.Request.Context(), userID)
+	if err != nil {
+		hkvilog.Errorf("获取会话列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取会话列表失败",
+		})
+		return
+	}
+
+	items := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, gin.H{
+			"id":           session.ID,
+			"device_name":  session.DeviceName,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"current":      session.ID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取成功",
+		"data": gin.H{
+			"sessions": items,
+		},
+	})
+}
+
+// RevokeSession 撤销指定的登录会话
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	sessionID := c.Param("id")
+
+	// 只能撤销属于自己的会话
+	session, err := cache.GetSession(c.Request.Context(), sessionID)
+	if err != nil || session.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "会话不存在",
+		})
+		return
+	}
+
+	if err := cache.DeleteSession(c.Request.Context(), userID, sessionID); err != nil {
+		hkvilog.Errorf("撤销会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "撤销会话失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "会话已撤销",
+	})
+}
+
+// RevokeAllSessions 撤销当前用户的所有登录会话
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userID := c.GetInt("user_id")
+
+	if err := cache.DeleteUserSessions(c.Request.Context(), userID); err != nil {
+		hkvilog.Errorf("撤销全部会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "撤销会话失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "所有会话已撤销",
+	})
+}
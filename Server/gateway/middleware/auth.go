@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"gateway/config"
+	"gateway/cache"
 	"gateway/utils"
 	"net/http"
 	"strings"
@@ -10,7 +10,7 @@ import (
 )
 
 // AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(accessKeys *utils.KeySet) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取Authorization
 		authHeader := c.GetHeader("Authorization")
@@ -34,11 +34,8 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		// 加载配置
-		cfg := config.LoadConfig()
-
 		// 解析访问令牌
-		claims, err := utils.ValidateAccessToken(tokenString, cfg.JWT.AccessSecretKey)
+		claims, err := utils.ValidateAccessToken(tokenString, accessKeys)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": err.Error(),
@@ -47,28 +44,52 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 检查令牌是否在黑名单中 (暂时注释，需要token ID)
-		// isBlacklisted, err := cache.IsTokenBlacklisted(claims.ID)
-		// if err != nil {
-		//	c.JSON(http.StatusInternalServerError, gin.H{
-		//		"error": "令牌验证失败",
-		//	})
-		//	c.Abort()
-		//	return
-		// }
+		// 检查令牌是否在黑名单中
+		isBlacklisted, err := cache.IsTokenBlacklisted(c.Request.Context(), claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "令牌验证失败",
+			})
+			c.Abort()
+			return
+		}
+
+		if isBlacklisted {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "令牌已失效",
+			})
+			c.Abort()
+			return
+		}
+
+		// 检查令牌所属会话是否已被撤销
+		sessionActive, err := cache.SessionExists(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "令牌验证失败",
+			})
+			c.Abort()
+			return
+		}
 
-		// if isBlacklisted {
-		//	c.JSON(http.StatusUnauthorized, gin.H{
-		//		"error": "令牌已失效",
-		//	})
-		//	c.Abort()
-		//	return
-		// }
+		if !sessionActive {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "会话已失效",
+			})
+			c.Abort()
+			return
+		}
 
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
-		// c.Set("token_id", claims.ID)
+		c.Set("roles", claims.Roles)
+		c.Set("permissions", claims.Permissions)
+		c.Set("session_id", claims.SessionID)
+		c.Set("token_id", claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
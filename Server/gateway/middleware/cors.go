@@ -2,26 +2,31 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CORSMiddleware CORS中间件
-func CORSMiddleware() gin.HandlerFunc {
+// allowOrigins为空或包含"*"时允许所有来源
+func CORSMiddleware(allowOrigins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		method := c.Request.Method
 		origin := c.Request.Header.Get("Origin")
 
 		// 设置允许的域名
-		if origin != "" {
+		if origin != "" && originAllowed(allowOrigins, origin) {
 			c.Header("Access-Control-Allow-Origin", origin)
 		}
 
 		// 设置允许的请求头
-		c.Header("Access-Control-Allow-Headers", "Content-Type, AccessToken, X-CSRF-Token, Authorization, Token, X-Token, X-User-Id")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, AccessToken, X-CSRF-Token, Authorization, Token, X-Token, X-User-Id, X-Request-ID")
+
+		// 允许前端读取请求ID响应头
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 
 		// 设置允许的请求方法
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
+		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE, UPDATE")
 
 		// 设置是否允许携带凭证
 		c.Header("Access-Control-Allow-Credentials", "true")
@@ -39,3 +44,16 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// originAllowed 检查来源是否在允许列表中
+func originAllowed(allowOrigins []string, origin string) bool {
+	if len(allowOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range allowOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"gateway/cache"
+	"gateway/config"
+	"gateway/metrics"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -23,7 +25,8 @@ func RateLimitMiddleware(keyPrefix string, maxRequests int, window time.Duration
 		key := fmt.Sprintf("%s:%s", keyPrefix, clientIP)
 
 		// 检查限流
-		if !checkRateLimit(key, maxRequests, window) {
+		if !checkRateLimit(c.Request.Context(), key, maxRequests, window) {
+			metrics.RateLimitRejectionsTotal.WithLabelValues(keyPrefix).Inc()
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "请求过于频繁，请稍后再试",
 			})
@@ -36,19 +39,17 @@ func RateLimitMiddleware(keyPrefix string, maxRequests int, window time.Duration
 }
 
 // SMSRateLimitMiddleware 短信限流中间件
-func SMSRateLimitMiddleware() gin.HandlerFunc {
-	return RateLimitMiddleware("sms_rate_limit", 5, time.Minute*1) // 1分钟内最多5次
+func SMSRateLimitMiddleware(rule config.RateLimitRule) gin.HandlerFunc {
+	return RateLimitMiddleware("sms_rate_limit", rule.MaxRequests, time.Duration(rule.Window)*time.Second)
 }
 
 // LoginRateLimitMiddleware 登录限流中间件
-func LoginRateLimitMiddleware() gin.HandlerFunc {
-	return RateLimitMiddleware("login_rate_limit", 10, time.Minute*5) // 5分钟内最多10次
+func LoginRateLimitMiddleware(rule config.RateLimitRule) gin.HandlerFunc {
+	return RateLimitMiddleware("login_rate_limit", rule.MaxRequests, time.Duration(rule.Window)*time.Second)
 }
 
 // checkRateLimit 检查限流
-func checkRateLimit(key string, maxRequests int, window time.Duration) bool {
-	ctx := context.Background()
-
+func checkRateLimit(ctx context.Context, key string, maxRequests int, window time.Duration) bool {
 	// 使用滑动窗口算法
 	now := time.Now().Unix()
 	windowStart := now - int64(window.Seconds())
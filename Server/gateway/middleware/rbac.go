@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"gateway/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 角色校验中间件，用户拥有任一指定角色即可访问
+// 需要在AuthMiddleware之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasAnyRole(c.GetStringSlice("roles"), roles) {
+			abortForbidden(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission 权限校验中间件，用户必须拥有全部指定权限才能访问
+// 需要在AuthMiddleware之后使用
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasAllPermissions(c.GetStringSlice("permissions"), permissions) {
+			abortForbidden(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AccessRuleMiddleware 按路径前缀进行访问控制的中间件，用于/api/business/*path等通配路由
+// 多条规则匹配时使用前缀最长的规则，未匹配任何规则的请求直接放行
+func AccessRuleMiddleware(rules []config.AccessRuleConfig) gin.HandlerFunc {
+	// 按前缀长度倒序排列，保证优先匹配最具体的规则
+	sorted := make([]config.AccessRuleConfig, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Prefix) > len(sorted[j].Prefix)
+	})
+
+	return func(c *gin.Context) {
+		rule := matchAccessRule(sorted, c.Request.Method, c.Request.URL.Path)
+		if rule == nil {
+			c.Next()
+			return
+		}
+
+		if len(rule.Roles) > 0 && !hasAnyRole(c.GetStringSlice("roles"), rule.Roles) {
+			abortForbidden(c)
+			return
+		}
+		if len(rule.Permissions) > 0 && !hasAllPermissions(c.GetStringSlice("permissions"), rule.Permissions) {
+			abortForbidden(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// matchAccessRule 查找与请求匹配的访问规则
+func matchAccessRule(rules []config.AccessRuleConfig, method, path string) *config.AccessRuleConfig {
+	for i := range rules {
+		rule := &rules[i]
+		if path != rule.Prefix && !strings.HasPrefix(path, strings.TrimSuffix(rule.Prefix, "/")+"/") {
+			continue
+		}
+		if len(rule.Methods) > 0 && !containsFold(rule.Methods, method) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// hasAnyRole 检查是否拥有任一所需角色
+func hasAnyRole(granted, required []string) bool {
+	for _, role := range required {
+		for _, g := range granted {
+			if g == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasAllPermissions 检查是否拥有全部所需权限
+// 支持通配符：* 表示全部权限，orders:* 表示orders下的全部权限
+func hasAllPermissions(granted, required []string) bool {
+	for _, permission := range required {
+		if !hasPermission(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPermission 检查是否拥有单个权限
+func hasPermission(granted []string, permission string) bool {
+	for _, g := range granted {
+		if g == "*" || g == permission {
+			return true
+		}
+		if strings.HasSuffix(g, ":*") && strings.HasPrefix(permission, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold 不区分大小写地检查切片是否包含指定值
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// abortForbidden 返回权限不足
+func abortForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "权限不足",
+	})
+	c.Abort()
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求ID的HTTP头
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey 上下文中保存请求ID的键
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength 接受客户端请求ID的最大长度
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware 请求ID中间件
+// 接受客户端传入的合法X-Request-ID，否则生成新的请求ID，并在所有响应中回显
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		// 统一请求头中的值，转发到上游时直接使用
+		c.Request.Header.Set(RequestIDHeader, requestID)
+		c.Set(RequestIDKey, requestID)
+
+		// 在处理前设置响应头，确保中止和错误响应同样携带请求ID
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// validRequestID 检查请求ID是否只包含安全字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, ch := range id {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成随机请求ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -6,47 +6,76 @@ import (
 	"gateway/middleware"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-// SetupRoutes 设置路由
-func SetupRoutes(r *gin.Engine, cfg *config.Config) {
+// SetupRoutes 设置路由，返回支持配置热加载的运行时组件
+func SetupRoutes(r *gin.Engine, cfg *config.Config) (*Runtime, error) {
+	rt, err := newRuntime(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// 使用中间件
-	r.Use(middleware.CORSMiddleware())   // CORS中间件
-	r.Use(middleware.LoggerMiddleware()) // 日志中间件
-	r.Use(middleware.ErrorHandler())     // 错误处理中间件
+	r.Use(middleware.RequestIDMiddleware())                                // 请求ID中间件
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName))                     // 链路追踪中间件
+	r.Use(middleware.MetricsMiddleware())                                  // 指标中间件
+	r.Use(rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.cors })) // CORS中间件
+	r.Use(middleware.LoggerMiddleware())                                   // 日志中间件
+	r.Use(middleware.ErrorHandler())                                       // 错误处理中间件
+
+	authMiddleware := rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.auth })
 
-	// 创建处理器实例
-	authHandler := handlers.NewAuthHandler(cfg)
+	// 公开令牌验证公钥
+	r.GET("/.well-known/jwks.json", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.JWKS }))
 
 	// API路由组
 	api := r.Group("/api")
 	{
 		// 健康检查接口
 		api.GET("/health", handlers.HealthCheck)
+		api.GET("/health/live", handlers.Liveness)                                                                   // 存活检查
+		api.GET("/health/ready", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.healthHandler.Readiness })) // 就绪检查
+
+		// 短信限流，只用于会发送短信的接口
+		smsRateLimit := rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.smsRateLimit })
 
 		// 认证相关接口（无需认证）
 		auth := api.Group("/auth")
-		auth.Use(middleware.LoginRateLimitMiddleware()) // 登录限流
+		auth.Use(rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.loginRateLimit })) // 登录限流
 		{
-			auth.POST("/login", authHandler.Login)          // 用户登录
-			auth.POST("/register", authHandler.Register)    // 用户注册
-			auth.POST("/sms/send", authHandler.SendSMS)     // 发送短信验证码
-			auth.POST("/sms/login", authHandler.SMSLogin)   // 短信验证码登录
-			auth.POST("/refresh", authHandler.RefreshToken) // 刷新令牌
+			auth.POST("/login", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.Login }))                                               // 用户登录
+			auth.POST("/register", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.Register }))                                         // 用户注册
+			auth.POST("/sms/send", smsRateLimit, rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.SendSMS }))                            // 发送短信验证码
+			auth.POST("/sms/login", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.SMSLogin }))                                        // 短信验证码登录
+			auth.POST("/refresh", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.RefreshToken }))                                      // 刷新令牌
+			auth.POST("/password/reset/request", smsRateLimit, rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.RequestPasswordReset })) // 申请重置密码
+			auth.POST("/password/reset/confirm", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.ConfirmPasswordReset }))               // 确认重置密码
 		}
 
 		// 需要认证的接口
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware()) // 使用认证中间件
+		protected.Use(authMiddleware) // 使用认证中间件
 		{
-			protected.POST("/auth/logout", authHandler.Logout) // 用户退出
+			protected.POST("/auth/logout", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.Logout })) // 用户退出
 
-			// 代理到业务服务的接口
-			business := protected.Group("/business")
+			// 登录会话管理接口
+			sessions := protected.Group("/auth/sessions")
 			{
-				// 这里可以添加需要代理到业务服务的路由
-				business.Any("/*path", handlers.ProxyToBusiness)
+				sessions.GET("", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.ListSessions }))         // 获取会话列表
+				sessions.DELETE("/:id", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.RevokeSession })) // 撤销指定会话
+				sessions.DELETE("", rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.authHandler.RevokeAllSessions })) // 撤销所有会话
 			}
 		}
 	}
+
+	// 其余请求按配置的路由表代理到上游服务
+	r.NoRoute(
+		rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.proxyHandler.MatchRoute }), // 匹配代理路由
+		rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.proxyAuth }),               // 按路由配置进行认证
+		rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.accessRules }),             // 按路径前缀的角色/权限校验
+		rt.dynamic(func(s *snapshot) gin.HandlerFunc { return s.proxyHandler.Serve }),      // 转发请求
+	)
+
+	return rt, nil
 }
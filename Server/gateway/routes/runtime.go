@@ -0,0 +1,169 @@
+package routes
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"gateway/config"
+	"gateway/handlers"
+	"gateway/middleware"
+	"gateway/upstream"
+	"gateway/utils"
+	"gateway/utils/hkvilog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Runtime 可热加载的运行时组件
+// 路由只注册一次，请求处理时从当前快照中取出处理器，配置重新加载后整体替换快照
+type Runtime struct {
+	mu      sync.Mutex // 串行化配置重新加载
+	current atomic.Pointer[snapshot]
+}
+
+// snapshot 由一份配置构建的全部处理器
+type snapshot struct {
+	cfg           *config.Config
+	logLevel      int
+	upstreams     *upstream.Registry
+	authHandler   *handlers.AuthHandler
+	proxyHandler  *handlers.ProxyHandler
+	healthHandler *handlers.HealthHandler
+
+	cors           gin.HandlerFunc
+	loginRateLimit gin.HandlerFunc
+	smsRateLimit   gin.HandlerFunc
+	auth           gin.HandlerFunc
+	proxyAuth      gin.HandlerFunc
+	accessRules    gin.HandlerFunc
+}
+
+// newRuntime 根据初始配置创建运行时组件并启动上游健康检查
+func newRuntime(cfg *config.Config) (*Runtime, error) {
+	s, err := newSnapshot(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyLog()
+	s.upstreams.StartHealthChecks()
+
+	rt := &Runtime{}
+	rt.current.Store(s)
+	return rt, nil
+}
+
+// newSnapshot 根据配置构建处理器，任一组件构建失败时返回错误
+// 上游配置未变化时复用prev的实例池，保留健康检查和熔断状态
+func newSnapshot(cfg *config.Config, prev *snapshot) (*snapshot, error) {
+	logLevel, err := hkvilog.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加载访问令牌密钥
+	accessKeys, err := utils.LoadAccessKeySet(&cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建上游实例池
+	var upstreams *upstream.Registry
+	if prev != nil && !upstreamsChanged(prev.cfg, cfg) {
+		upstreams = prev.upstreams
+	} else if upstreams, err = upstream.NewRegistry(cfg); err != nil {
+		return nil, err
+	}
+	businessPool, _ := upstreams.Get(upstream.DefaultName)
+
+	// 创建处理器实例
+	proxyHandler, err := handlers.NewProxyHandler(cfg, upstreams)
+	if err != nil {
+		return nil, err
+	}
+	authMiddleware := middleware.AuthMiddleware(accessKeys)
+
+	return &snapshot{
+		cfg:            cfg,
+		logLevel:       logLevel,
+		upstreams:      upstreams,
+		authHandler:    handlers.NewAuthHandler(cfg, accessKeys, businessPool),
+		proxyHandler:   proxyHandler,
+		healthHandler:  handlers.NewHealthHandler(businessPool),
+		cors:           middleware.CORSMiddleware(cfg.CORS.AllowOrigins),
+		loginRateLimit: middleware.LoginRateLimitMiddleware(cfg.RateLimit.Login),
+		smsRateLimit:   middleware.SMSRateLimitMiddleware(cfg.RateLimit.SMS),
+		auth:           authMiddleware,
+		proxyAuth:      proxyHandler.RequireAuth(authMiddleware),
+		accessRules:    middleware.AccessRuleMiddleware(cfg.AccessRules),
+	}, nil
+}
+
+// upstreamsChanged 检查上游相关配置是否变化
+func upstreamsChanged(old, new *config.Config) bool {
+	return !reflect.DeepEqual(old.BusinessAPI, new.BusinessAPI) ||
+		!reflect.DeepEqual(old.Upstreams, new.Upstreams) ||
+		!reflect.DeepEqual(old.Transport, new.Transport)
+}
+
+// applyLog 应用日志级别和格式
+func (s *snapshot) applyLog() {
+	hkvilog.SetLevel(s.logLevel)
+	hkvilog.SetFormat(s.cfg.Log.Format)
+}
+
+// Config 返回当前生效的配置
+func (rt *Runtime) Config() *config.Config {
+	return rt.current.Load().cfg
+}
+
+// Reload 应用新配置，构建失败时保留原配置并返回错误
+// 限流、上游及路由、日志级别、跨域来源和JWT密钥立即生效，服务端口和Redis配置需要重启
+func (rt *Runtime) Reload(cfg *config.Config) error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	old := rt.current.Load()
+	changes := config.Diff(old.cfg, cfg)
+	if len(changes) == 0 {
+		hkvilog.Info("配置未变化")
+		return nil
+	}
+
+	next, err := newSnapshot(cfg, old)
+	if err != nil {
+		return err
+	}
+
+	next.applyLog()
+	if next.upstreams != old.upstreams {
+		next.upstreams.StartHealthChecks()
+	}
+	rt.current.Store(next)
+	if next.upstreams != old.upstreams {
+		old.upstreams.Stop()
+	}
+
+	for _, change := range changes {
+		if config.RequiresRestart(change) {
+			hkvilog.Warnf("配置变更（需要重启后生效）: %s", change)
+			continue
+		}
+		hkvilog.Infof("配置变更: %s", change)
+	}
+
+	return nil
+}
+
+// Stop 停止运行时组件的后台任务
+func (rt *Runtime) Stop() {
+	rt.current.Load().upstreams.Stop()
+}
+
+// dynamic 返回从当前快照中取出处理器的处理函数
+func (rt *Runtime) dynamic(pick func(s *snapshot) gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pick(rt.current.Load())(c)
+	}
+}
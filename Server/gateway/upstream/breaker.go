@@ -0,0 +1,161 @@
+package upstream
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gateway/config"
+	"gateway/utils/hkvilog"
+)
+
+// 熔断器状态
+const (
+	StateClosed   = "closed"    // 关闭：正常放行
+	StateOpen     = "open"      // 打开：直接拒绝
+	StateHalfOpen = "half-open" // 半开：放行少量试探请求
+)
+
+// ErrCircuitOpen 熔断器已打开
+var ErrCircuitOpen = errors.New("上游服务熔断中")
+
+// CircuitBreaker 熔断器
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenRequests int
+
+	mu        sync.Mutex
+	state     string
+	failures  int       // 关闭状态下的连续失败次数
+	openedAt  time.Time // 进入打开状态的时间
+	inFlight  int       // 半开状态下正在进行的试探请求数
+	successes int       // 半开状态下的试探成功次数
+}
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(name string, cfg config.CircuitBreakerConfig) *CircuitBreaker {
+	openTimeout := time.Duration(cfg.OpenTimeout) * time.Second
+	if openTimeout <= 0 {
+		openTimeout = 30 * time.Second
+	}
+	halfOpenRequests := cfg.HalfOpenRequests
+	if halfOpenRequests <= 0 {
+		halfOpenRequests = 1
+	}
+
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: cfg.FailureThreshold,
+		openTimeout:      openTimeout,
+		halfOpenRequests: halfOpenRequests,
+		state:            StateClosed,
+	}
+}
+
+// State 返回熔断器当前状态
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow 检查是否允许请求通过，允许时必须调用OnSuccess或OnFailure报告结果
+func (b *CircuitBreaker) Allow() error {
+	if b.failureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.inFlight >= b.halfOpenRequests {
+			return ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+
+	return nil
+}
+
+// OnSuccess 报告请求成功
+func (b *CircuitBreaker) OnSuccess() {
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+	case StateHalfOpen:
+		b.inFlight--
+		b.successes++
+		if b.successes >= b.halfOpenRequests {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+// OnFailure 报告请求失败
+func (b *CircuitBreaker) OnFailure() {
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		// 试探失败，重新打开
+		b.inFlight--
+		b.setState(StateOpen)
+	}
+}
+
+// OnCancel 请求被调用方取消，不计入成功或失败
+func (b *CircuitBreaker) OnCancel() {
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.inFlight--
+	}
+}
+
+// setState 切换状态，调用方需持有锁
+func (b *CircuitBreaker) setState(state string) {
+	if b.state == state {
+		return
+	}
+
+	hkvilog.Warnf("上游 %s 熔断器状态变更: %s -> %s", b.name, b.state, state)
+	b.state = state
+	b.failures = 0
+	b.inFlight = 0
+	b.successes = 0
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,397 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gateway/config"
+	"gateway/utils/hkvilog"
+)
+
+// 负载均衡策略
+const (
+	StrategyRoundRobin     = "round_robin"     // 轮询
+	StrategyLeastConn      = "least_conn"      // 最少连接
+	StrategyConsistentHash = "consistent_hash" // 一致性哈希（按用户ID）
+)
+
+// virtualNodes 一致性哈希环上每个实例的虚拟节点数
+const virtualNodes = 100
+
+// ErrNoAvailableInstance 没有可用的上游实例
+var ErrNoAvailableInstance = errors.New("没有可用的上游实例")
+
+// Instance 上游实例
+type Instance struct {
+	URL *url.URL // 实例地址
+
+	healthy atomic.Bool  // 主动健康检查结果
+	active  atomic.Int64 // 正在处理的请求数
+
+	mu            sync.Mutex
+	failures      int       // 被动检查连续失败次数
+	ejectedUntil  time.Time // 被动摘除截止时间
+	checkSuccess  int       // 主动检查连续成功次数
+	checkFailures int       // 主动检查连续失败次数
+}
+
+// Available 实例是否可以接收请求
+func (i *Instance) Available() bool {
+	if !i.healthy.Load() {
+		return false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return !time.Now().Before(i.ejectedUntil)
+}
+
+// ringNode 一致性哈希环节点
+type ringNode struct {
+	hash     uint32
+	instance *Instance
+}
+
+// Pool 上游实例池
+type Pool struct {
+	Name    string        // 上游名称
+	Timeout time.Duration // 默认请求超时时间
+
+	instances []*Instance
+	strategy  string
+	counter   atomic.Uint64
+	ring      []ringNode
+	cfg       config.LoadBalanceConfig
+	breaker   *CircuitBreaker
+	retry     retryPolicy
+	transport http.RoundTripper // 共用的连接池，每次发送都会创建客户端span
+	client    *http.Client      // 请求上游的客户端，经由Pool完成实例选择、熔断和重试
+	checker   *http.Client      // 健康检查客户端
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewPool 创建上游实例池，transport为所有上游共用的连接池
+func NewPool(upstreamCfg config.UpstreamConfig, transport http.RoundTripper) (*Pool, error) {
+	name := upstreamCfg.Name
+	cfg := upstreamCfg.LoadBalance
+
+	targets := upstreamCfg.Instances
+	if len(targets) == 0 && upstreamCfg.BaseURL != "" {
+		targets = []string{upstreamCfg.BaseURL}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("上游 %s 未配置实例", name)
+	}
+
+	strategy := cfg.Strategy
+	if strategy == "" {
+		strategy = StrategyRoundRobin
+	}
+	if strategy != StrategyRoundRobin && strategy != StrategyLeastConn && strategy != StrategyConsistentHash {
+		return nil, fmt.Errorf("上游 %s 的负载均衡策略无效: %s", name, strategy)
+	}
+
+	p := &Pool{
+		Name:      name,
+		Timeout:   time.Duration(upstreamCfg.Timeout) * time.Second,
+		strategy:  strategy,
+		cfg:       cfg,
+		breaker:   NewCircuitBreaker(name, upstreamCfg.CircuitBreaker),
+		retry:     newRetryPolicy(upstreamCfg.Retry),
+		transport: newTracedTransport(name, transport),
+		stop:      make(chan struct{}),
+	}
+	p.client = &http.Client{
+		Transport: p,
+		Timeout:   p.Timeout,
+	}
+
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("上游 %s 的URL无效: %s", name, target)
+		}
+
+		instance := &Instance{URL: u}
+		instance.healthy.Store(true)
+		p.instances = append(p.instances, instance)
+	}
+
+	if strategy == StrategyConsistentHash {
+		p.buildRing()
+	}
+
+	checkTimeout := time.Duration(cfg.HealthCheck.Timeout) * time.Second
+	if checkTimeout <= 0 {
+		checkTimeout = 2 * time.Second
+	}
+	// 健康检查不产生追踪数据，直接使用连接池
+	p.checker = &http.Client{
+		Transport: transport,
+		Timeout:   checkTimeout,
+	}
+
+	return p, nil
+}
+
+// buildRing 构建一致性哈希环
+func (p *Pool) buildRing() {
+	for _, instance := range p.instances {
+		for v := 0; v < virtualNodes; v++ {
+			key := instance.URL.String() + "#" + strconv.Itoa(v)
+			p.ring = append(p.ring, ringNode{
+				hash:     crc32.ChecksumIEEE([]byte(key)),
+				instance: instance,
+			})
+		}
+	}
+
+	sort.Slice(p.ring, func(i, j int) bool {
+		return p.ring[i].hash < p.ring[j].hash
+	})
+}
+
+// Client 返回请求该上游的HTTP客户端
+func (p *Pool) Client() *http.Client {
+	return p.client
+}
+
+// Breaker 返回该上游的熔断器
+func (p *Pool) Breaker() *CircuitBreaker {
+	return p.breaker
+}
+
+// Instances 返回实例列表
+func (p *Pool) Instances() []*Instance {
+	return p.instances
+}
+
+// Pick 按负载均衡策略选择一个可用实例
+// key用于一致性哈希，通常为用户ID，为空时退化为轮询
+func (p *Pool) Pick(key string) (*Instance, error) {
+	switch {
+	case p.strategy == StrategyLeastConn:
+		return p.pickLeastConn()
+	case p.strategy == StrategyConsistentHash && key != "":
+		return p.pickConsistentHash(key)
+	default:
+		return p.pickRoundRobin()
+	}
+}
+
+// pickRoundRobin 轮询选择实例
+func (p *Pool) pickRoundRobin() (*Instance, error) {
+	n := uint64(len(p.instances))
+	start := p.counter.Add(1) - 1
+	for i := uint64(0); i < n; i++ {
+		instance := p.instances[(start+i)%n]
+		if instance.Available() {
+			return instance, nil
+		}
+	}
+	return nil, ErrNoAvailableInstance
+}
+
+// pickLeastConn 选择正在处理请求数最少的实例
+func (p *Pool) pickLeastConn() (*Instance, error) {
+	var best *Instance
+	for _, instance := range p.instances {
+		if !instance.Available() {
+			continue
+		}
+		if best == nil || instance.active.Load() < best.active.Load() {
+			best = instance
+		}
+	}
+	if best == nil {
+		return nil, ErrNoAvailableInstance
+	}
+	return best, nil
+}
+
+// pickConsistentHash 按一致性哈希选择实例，目标实例不可用时顺时针选择下一个可用实例
+func (p *Pool) pickConsistentHash(key string) (*Instance, error) {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(p.ring), func(i int) bool {
+		return p.ring[i].hash >= hash
+	})
+
+	for i := 0; i < len(p.ring); i++ {
+		node := p.ring[(start+i)%len(p.ring)]
+		if node.instance.Available() {
+			return node.instance, nil
+		}
+	}
+	return nil, ErrNoAvailableInstance
+}
+
+// Acquire 标记实例开始处理请求
+func (p *Pool) Acquire(instance *Instance) {
+	instance.active.Add(1)
+}
+
+// Release 标记实例完成请求处理
+func (p *Pool) Release(instance *Instance) {
+	instance.active.Add(-1)
+}
+
+// ReportSuccess 报告请求成功，清零被动检查的失败计数
+func (p *Pool) ReportSuccess(instance *Instance) {
+	instance.mu.Lock()
+	instance.failures = 0
+	instance.mu.Unlock()
+}
+
+// ReportFailure 报告请求失败（5xx或连接错误），连续失败达到阈值时摘除实例
+func (p *Pool) ReportFailure(instance *Instance) {
+	maxFailures := p.cfg.PassiveCheck.MaxFailures
+	if maxFailures <= 0 {
+		return
+	}
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	instance.failures++
+	if instance.failures < maxFailures {
+		return
+	}
+
+	ejectDuration := time.Duration(p.cfg.PassiveCheck.EjectDuration) * time.Second
+	if ejectDuration <= 0 {
+		ejectDuration = 30 * time.Second
+	}
+	instance.failures = 0
+	instance.ejectedUntil = time.Now().Add(ejectDuration)
+	hkvilog.Warnf("上游 %s 实例 %s 连续失败 %d 次，摘除 %v", p.Name, instance.URL, maxFailures, ejectDuration)
+}
+
+// StartHealthCheck 启动主动健康检查
+func (p *Pool) StartHealthCheck() {
+	interval := time.Duration(p.cfg.HealthCheck.Interval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		p.checkAll()
+		for {
+			select {
+			case <-ticker.C:
+				p.checkAll()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止主动健康检查
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// checkAll 检查所有实例
+func (p *Pool) checkAll() {
+	var wg sync.WaitGroup
+	for _, instance := range p.instances {
+		wg.Add(1)
+		go func(instance *Instance) {
+			defer wg.Done()
+			p.updateHealth(instance, p.probe(context.Background(), instance) == nil)
+		}(instance)
+	}
+	wg.Wait()
+}
+
+// Ping 检查上游是否可达：依次探测可用实例的健康检查接口，任一实例正常即返回nil
+// 探测不经过熔断器和重试，也不影响实例的健康状态
+func (p *Pool) Ping(ctx context.Context) error {
+	err := ErrNoAvailableInstance
+	for _, instance := range p.instances {
+		if !instance.Available() {
+			continue
+		}
+		if err = p.probe(ctx, instance); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// probe 请求实例的健康检查接口
+func (p *Pool) probe(ctx context.Context, instance *Instance) error {
+	path := p.cfg.HealthCheck.Path
+	if path == "" {
+		path = "/api/health/ready"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, instance.URL.JoinPath(path).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.checker.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("实例 %s 健康检查返回 %d", instance.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// updateHealth 根据检查结果更新实例健康状态
+func (p *Pool) updateHealth(instance *Instance, ok bool) {
+	healthyThreshold := p.cfg.HealthCheck.HealthyThreshold
+	if healthyThreshold <= 0 {
+		healthyThreshold = 1
+	}
+	unhealthyThreshold := p.cfg.HealthCheck.UnhealthyThreshold
+	if unhealthyThreshold <= 0 {
+		unhealthyThreshold = 1
+	}
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	if ok {
+		instance.checkFailures = 0
+		instance.checkSuccess++
+		if instance.checkSuccess < healthyThreshold {
+			return
+		}
+		// 实例已恢复，重新接入（被动摘除的实例在摘除到期后接入）
+		if !instance.healthy.Load() {
+			instance.healthy.Store(true)
+			hkvilog.Infof("上游 %s 实例 %s 已恢复", p.Name, instance.URL)
+		}
+		return
+	}
+
+	instance.checkSuccess = 0
+	instance.checkFailures++
+	if instance.checkFailures >= unhealthyThreshold && instance.healthy.Load() {
+		instance.healthy.Store(false)
+		hkvilog.Warnf("上游 %s 实例 %s 健康检查失败，暂停转发", p.Name, instance.URL)
+	}
+}
@@ -0,0 +1,70 @@
+package upstream
+
+import (
+	"fmt"
+	"net/http"
+
+	"gateway/config"
+)
+
+// DefaultName 由business_api配置生成的默认上游名称
+const DefaultName = "business"
+
+// Registry 上游实例池注册表
+type Registry struct {
+	pools     map[string]*Pool
+	transport *http.Transport // 所有上游共用的连接池
+}
+
+// NewRegistry 根据配置创建所有上游实例池，business_api始终作为名为business的默认上游
+func NewRegistry(cfg *config.Config) (*Registry, error) {
+	upstreamConfigs := []config.UpstreamConfig{{
+		Name:           DefaultName,
+		BaseURL:        cfg.BusinessAPI.BaseURL,
+		Instances:      cfg.BusinessAPI.Instances,
+		Timeout:        cfg.BusinessAPI.Timeout,
+		LoadBalance:    cfg.BusinessAPI.LoadBalance,
+		CircuitBreaker: cfg.BusinessAPI.CircuitBreaker,
+		Retry:          cfg.BusinessAPI.Retry,
+	}}
+	upstreamConfigs = append(upstreamConfigs, cfg.Upstreams...)
+
+	r := &Registry{
+		pools:     make(map[string]*Pool, len(upstreamConfigs)),
+		transport: NewTransport(cfg.Transport),
+	}
+	for _, upstreamCfg := range upstreamConfigs {
+		if upstreamCfg.Name == "" {
+			return nil, fmt.Errorf("上游缺少名称")
+		}
+
+		pool, err := NewPool(upstreamCfg, r.transport)
+		if err != nil {
+			return nil, err
+		}
+		r.pools[upstreamCfg.Name] = pool
+	}
+
+	return r, nil
+}
+
+// Get 获取指定名称的上游实例池
+func (r *Registry) Get(name string) (*Pool, bool) {
+	pool, ok := r.pools[name]
+	return pool, ok
+}
+
+// StartHealthChecks 启动所有上游的主动健康检查
+func (r *Registry) StartHealthChecks() {
+	for _, pool := range r.pools {
+		pool.StartHealthCheck()
+	}
+}
+
+// Stop 停止所有上游的主动健康检查并关闭空闲连接
+func (r *Registry) Stop() {
+	for _, pool := range r.pools {
+		pool.Stop()
+	}
+	r.transport.CloseIdleConnections()
+}
@@ -0,0 +1,159 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gateway/metrics"
+)
+
+// hashKeyContextKey 请求上下文中保存一致性哈希键的键
+type hashKeyContextKey struct{}
+
+// WithHashKey 在请求上下文中设置一致性哈希键（通常为用户ID）
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyContextKey{}, key)
+}
+
+// NewRequest 创建发往该上游的请求，path为上游内的路径，主机部分由实例池在发送时替换
+func (p *Pool) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, "http://"+p.Name+path, body)
+}
+
+// RoundTrip 实现http.RoundTripper：选择实例、熔断检查、被动健康检查以及失败重试
+// 请求URL中的scheme和host会被替换为所选实例的地址，路径拼接在实例基础路径之后
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	startTime := time.Now()
+	resp, err := p.roundTrip(req)
+
+	metrics.UpstreamRequestDuration.WithLabelValues(p.Name).Observe(time.Since(startTime).Seconds())
+	if reason := errorReason(resp, err); reason != "" {
+		metrics.UpstreamErrorsTotal.WithLabelValues(p.Name, reason).Inc()
+	}
+	return resp, err
+}
+
+// roundTrip 执行带熔断和重试的上游请求
+func (p *Pool) roundTrip(req *http.Request) (*http.Response, error) {
+	if err := p.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	hashKey, _ := req.Context().Value(hashKeyContextKey{}).(string)
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// 等待期间客户端取消请求：上一次的响应已关闭，不能返回给调用方，也不计入熔断失败
+			if sleepErr := sleepContext(req.Context(), p.retry.backoff(attempt)); sleepErr != nil {
+				p.breaker.OnCancel()
+				return nil, sleepErr
+			}
+		}
+
+		var instance *Instance
+		instance, err = p.Pick(hashKey)
+		if err != nil {
+			resp = nil
+			break
+		}
+
+		resp, err = p.send(req, instance, attempt)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			p.ReportSuccess(instance)
+			p.breaker.OnSuccess()
+			return resp, nil
+		}
+
+		if errors.Is(err, context.Canceled) {
+			p.breaker.OnCancel()
+			return nil, err
+		}
+		p.ReportFailure(instance)
+
+		if attempt >= p.retry.maxRetries || !canRetry(req, resp, err) {
+			break
+		}
+
+		// 丢弃本次失败的响应，准备重试
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+
+	p.breaker.OnFailure()
+	return resp, err
+}
+
+// errorReason 返回上游请求失败的原因，成功时返回空字符串
+func errorReason(resp *http.Response, err error) string {
+	switch {
+	case err == nil && resp.StatusCode >= http.StatusInternalServerError:
+		return "status_5xx"
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrNoAvailableInstance):
+		return "no_instance"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// send 向指定实例发送一次请求
+func (p *Pool) send(req *http.Request, instance *Instance, attempt int) (*http.Response, error) {
+	outreq := req.Clone(req.Context())
+	outreq.URL.Scheme = instance.URL.Scheme
+	outreq.URL.Host = instance.URL.Host
+	outreq.URL.Path = strings.TrimSuffix(instance.URL.Path, "/") + req.URL.Path
+	outreq.URL.RawPath = ""
+	outreq.Host = instance.URL.Host
+
+	// 重试时重新获取请求体
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		outreq.Body = body
+	}
+
+	p.Acquire(instance)
+	resp, err := p.transport.RoundTrip(outreq)
+	if err != nil {
+		p.Release(instance)
+		return nil, err
+	}
+
+	// 响应体读取完毕后才释放实例，使最少连接策略统计的是真实的在途请求
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() { p.Release(instance) }}
+	return resp, nil
+}
+
+// releaseOnClose 关闭时释放实例的响应体
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	closed  bool
+}
+
+// Close 关闭响应体并释放实例
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	if !r.closed {
+		r.closed = true
+		r.release()
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"gateway/config"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// NewTransport 根据配置创建上游共用的连接池，避免每次请求新建连接
+func NewTransport(cfg config.TransportConfig) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   secondsOr(cfg.DialTimeout, 5),
+			KeepAlive: secondsOr(cfg.KeepAlive, 30),
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       secondsOr(cfg.IdleConnTimeout, 90),
+		TLSHandshakeTimeout:   secondsOr(cfg.TLSHandshakeTimeout, 5),
+		ResponseHeaderTimeout: time.Duration(cfg.ResponseHeaderTimeout) * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+	}
+}
+
+// secondsOr 将秒数转换为时长，未配置时使用默认值
+func secondsOr(seconds, fallback int) time.Duration {
+	if seconds <= 0 {
+		seconds = fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// idempotentMethods 幂等的请求方法，失败后可以安全重试
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	http.MethodTrace:   true,
+}
+
+// retryPolicy 重试策略
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// newRetryPolicy 根据配置创建重试策略
+func newRetryPolicy(cfg config.RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxRetries: cfg.MaxRetries,
+		baseDelay:  time.Duration(cfg.BaseDelay) * time.Millisecond,
+		maxDelay:   time.Duration(cfg.MaxDelay) * time.Millisecond,
+	}
+	if policy.baseDelay <= 0 {
+		policy.baseDelay = 50 * time.Millisecond
+	}
+	if policy.maxDelay < policy.baseDelay {
+		policy.maxDelay = policy.baseDelay
+	}
+	return policy
+}
+
+// backoff 计算第attempt次重试前的等待时间（指数退避 + 全抖动）
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.baseDelay << uint(attempt-1)
+	if delay <= 0 || delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// canRetry 判断失败的请求能否重试
+// 幂等请求在连接错误或502/503/504时重试；非幂等请求只在连接尚未建立（请求未发出）时重试
+func canRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false // 请求体无法重放
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		if idempotentMethods[req.Method] {
+			return true
+		}
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	if !idempotentMethods[req.Method] {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleepContext 等待指定时间，上下文结束时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newTracedTransport 包装连接池，为发往实例的每次请求（包括重试）创建客户端span并注入traceparent
+func newTracedTransport(name string, transport http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(transport, otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+		return fmt.Sprintf("%s %s", name, req.Method)
+	}))
+}
@@ -1,10 +1,16 @@
 package hkvilog
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,6 +23,12 @@ const (
 	FATAL
 )
 
+// 日志格式
+const (
+	FormatText = "text" // 带颜色的文本，便于本地查看
+	FormatJSON = "json" // 每行一个JSON对象，便于Loki/ELK采集
+)
+
 // 日志级别名称
 var levelNames = []string{
 	"DEBUG",
@@ -37,26 +49,162 @@ var levelColors = []string{
 
 const colorReset = "\033[0m"
 
-// Logger 日志记录器
+// 全局输出设置，可以在运行时修改
+var (
+	level      atomic.Int32
+	jsonFormat atomic.Bool
+	color      atomic.Bool
+
+	mu        sync.Mutex // 保证多个goroutine的日志行不会交错
+	output    io.Writer  = os.Stdout
+	errOutput io.Writer  // ERROR及以上级别日志的额外输出，为nil时不单独输出
+)
+
+func init() {
+	level.Store(INFO)
+	color.Store(true)
+}
+
+// Logger 带有附加字段的日志记录器
+// Logger创建后不可修改，可以在多个goroutine中并发使用
 type Logger struct {
-	level  int
-	logger *log.Logger
+	fields []interface{} // 键值对
 }
 
-// 全局日志记录器
-var globalLogger = &Logger{
-	level:  INFO,
-	logger: log.New(os.Stdout, "", 0),
+// std 不带字段的默认日志记录器
+var std = &Logger{}
+
+// SetLevel 设置日志级别，低于该级别的日志不输出
+func SetLevel(l int) {
+	level.Store(int32(l))
 }
 
-// SetLevel 设置日志级别
-func SetLevel(level int) {
-	globalLogger.level = level
+// ParseLevel 解析日志级别名称（不区分大小写），为空时返回INFO
+func ParseLevel(name string) (int, error) {
+	if name == "" {
+		return INFO, nil
+	}
+	for l, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return l, nil
+		}
+	}
+	return INFO, fmt.Errorf("无效的日志级别: %s", name)
 }
 
-// logf 格式化输出日志
-func (l *Logger) logf(level int, format string, v ...interface{}) {
-	if level < l.level {
+// SetFormat 设置日志格式：text（默认）或json
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		jsonFormat.Store(false)
+	case FormatJSON:
+		jsonFormat.Store(true)
+	default:
+		return fmt.Errorf("无效的日志格式: %s", format)
+	}
+	return nil
+}
+
+// SetOutput 设置日志输出位置，默认为标准输出
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+}
+
+// SetErrorOutput 设置ERROR及以上级别日志的额外输出位置，为nil时不单独输出
+func SetErrorOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	errOutput = w
+}
+
+// SetColor 设置文本格式日志是否带颜色，写入文件时应关闭
+func SetColor(enabled bool) {
+	color.Store(enabled)
+}
+
+// FileOptions 文件日志配置
+type FileOptions struct {
+	Path       string // 日志文件路径
+	ErrorPath  string // 错误日志文件路径，ERROR及以上级别的日志同时写入该文件，为空时不单独输出
+	Stdout     bool   // 写入文件的同时输出到标准输出
+	MaxSize    int    // 单个文件最大大小（MB），为0时不按大小轮转
+	Rotate     string // 按时间轮转：hourly、daily，为空时不按时间轮转
+	MaxBackups int    // 保留的轮转文件数，为0时全部保留
+	Compress   bool   // 使用gzip压缩轮转后的文件
+}
+
+// SetupFileOutput 将日志写入轮转文件，返回关闭日志文件并恢复标准输出的函数
+// 写入文件时文本格式的日志不带颜色
+func SetupFileOutput(opts FileOptions) (func(), error) {
+	rotateOptions := func(filename string) RotateOptions {
+		return RotateOptions{
+			Filename:   filename,
+			MaxSize:    opts.MaxSize,
+			Rotate:     opts.Rotate,
+			MaxBackups: opts.MaxBackups,
+			Compress:   opts.Compress,
+		}
+	}
+
+	file, err := NewRotatingFile(rotateOptions(opts.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	var errFile *RotatingFile
+	if opts.ErrorPath != "" {
+		if errFile, err = NewRotatingFile(rotateOptions(opts.ErrorPath)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	var out io.Writer = file
+	if opts.Stdout {
+		out = io.MultiWriter(os.Stdout, file)
+	}
+
+	mu.Lock()
+	output = out
+	if errFile != nil {
+		errOutput = errFile
+	}
+	mu.Unlock()
+	SetColor(false)
+
+	return func() {
+		mu.Lock()
+		output = os.Stdout
+		errOutput = nil
+		mu.Unlock()
+
+		file.Close()
+		if errFile != nil {
+			errFile.Close()
+		}
+	}, nil
+}
+
+// With 返回附加了字段的日志记录器，参数为交替的键和值
+//
+//	hkvilog.With("user_id", 42, "route", "/api/auth/login").Info("登录成功")
+func With(keyvals ...interface{}) *Logger {
+	return std.With(keyvals...)
+}
+
+// With 返回在当前字段基础上附加新字段的日志记录器
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{fields: fields}
+}
+
+// log 输出一条日志，调用方必须是直接被业务代码调用的导出函数，以便正确记录调用位置
+func (l *Logger) log(lvl int, msg string, extra ...interface{}) {
+	if int32(lvl) < level.Load() {
 		return
 	}
 
@@ -75,74 +223,187 @@ func (l *Logger) logf(level int, format string, v ...interface{}) {
 		}
 	}
 
-	// 格式化时间
-	now := time.Now()
-	timestamp := now.Format("2006-01-02 15:04:05")
-
-	// 构建日志消息
-	msg := fmt.Sprintf(format, v...)
-	levelName := levelNames[level]
-	levelColor := levelColors[level]
+	fields := l.fields
+	if len(extra) > 0 {
+		fields = append(append([]interface{}{}, l.fields...), extra...)
+	}
 
-	// 输出带颜色的日志
-	logLine := fmt.Sprintf("%s[%s]%s %s %s:%d %s",
-		levelColor, levelName, colorReset,
-		timestamp, file, line, msg)
+	var buf bytes.Buffer
+	if jsonFormat.Load() {
+		writeJSON(&buf, lvl, file, line, msg, fields)
+	} else {
+		writeText(&buf, lvl, file, line, msg, fields)
+	}
 
-	l.logger.Print(logLine)
+	mu.Lock()
+	output.Write(buf.Bytes())
+	if lvl >= ERROR && errOutput != nil {
+		errOutput.Write(buf.Bytes())
+	}
+	mu.Unlock()
 
 	// 如果是FATAL级别，退出程序
-	if level == FATAL {
+	if lvl == FATAL {
 		os.Exit(1)
 	}
 }
 
-// Debug 调试日志
-func Debug(v ...interface{}) {
-	globalLogger.logf(DEBUG, fmt.Sprint(v...))
+// writeText 输出带颜色的文本格式日志，字段以key=value形式附加在消息之后
+func writeText(buf *bytes.Buffer, lvl int, file string, line int, msg string, fields []interface{}) {
+	if color.Load() {
+		fmt.Fprintf(buf, "%s[%s]%s ", levelColors[lvl], levelNames[lvl], colorReset)
+	} else {
+		fmt.Fprintf(buf, "[%s] ", levelNames[lvl])
+	}
+	fmt.Fprintf(buf, "%s %s:%d %s", time.Now().Format("2006-01-02 15:04:05"), file, line, msg)
+
+	var stack string
+	eachField(fields, func(key string, value interface{}) {
+		if key == "stack" {
+			stack = fmt.Sprint(value)
+			return
+		}
+		text := fmt.Sprint(fieldValue(value))
+		if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+			text = fmt.Sprintf("%q", text)
+		}
+		fmt.Fprintf(buf, " %s=%s", key, text)
+	})
+	buf.WriteByte('\n')
+
+	// 堆栈单独输出，保持可读
+	if stack != "" {
+		buf.WriteString(stack)
+		if !strings.HasSuffix(stack, "\n") {
+			buf.WriteByte('\n')
+		}
+	}
 }
 
-// Debugf 格式化调试日志
-func Debugf(format string, v ...interface{}) {
-	globalLogger.logf(DEBUG, format, v...)
+// writeJSON 输出单行JSON格式日志
+func writeJSON(buf *bytes.Buffer, lvl int, file string, line int, msg string, fields []interface{}) {
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, time.Now().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, strings.ToLower(levelNames[lvl]))
+	buf.WriteString(`,"caller":`)
+	writeJSONValue(buf, fmt.Sprintf("%s:%d", file, line))
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, msg)
+
+	eachField(fields, func(key string, value interface{}) {
+		buf.WriteByte(',')
+		writeJSONValue(buf, key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, fieldValue(value))
+	})
+	buf.WriteString("}\n")
 }
 
-// Info 信息日志
-func Info(v ...interface{}) {
-	globalLogger.logf(INFO, fmt.Sprint(v...))
+// writeJSONValue 写入JSON值，无法序列化时写入其字符串形式
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(data)
 }
 
-// Infof 格式化信息日志
-func Infof(format string, v ...interface{}) {
-	globalLogger.logf(INFO, format, v...)
+// eachField 遍历键值对，键不是字符串或缺少值时使用占位键名
+func eachField(fields []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(fields[i])
+		}
+		if i+1 >= len(fields) {
+			fn("!BADKEY", fields[i])
+			return
+		}
+		fn(key, fields[i+1])
+	}
 }
 
-// Warn 警告日志
-func Warn(v ...interface{}) {
-	globalLogger.logf(WARN, fmt.Sprint(v...))
+// fieldValue 将错误和时长等类型转换为便于阅读的字符串
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return value
+	}
 }
 
+// Debug 调试日志
+func (l *Logger) Debug(v ...interface{}) { l.log(DEBUG, fmt.Sprint(v...)) }
+
+// Debugf 格式化调试日志
+func (l *Logger) Debugf(format string, v ...interface{}) { l.log(DEBUG, fmt.Sprintf(format, v...)) }
+
+// Info 信息日志
+func (l *Logger) Info(v ...interface{}) { l.log(INFO, fmt.Sprint(v...)) }
+
+// Infof 格式化信息日志
+func (l *Logger) Infof(format string, v ...interface{}) { l.log(INFO, fmt.Sprintf(format, v...)) }
+
+// Warn 警告日志
+func (l *Logger) Warn(v ...interface{}) { l.log(WARN, fmt.Sprint(v...)) }
+
 // Warnf 格式化警告日志
-func Warnf(format string, v ...interface{}) {
-	globalLogger.logf(WARN, format, v...)
-}
+func (l *Logger) Warnf(format string, v ...interface{}) { l.log(WARN, fmt.Sprintf(format, v...)) }
 
 // Error 错误日志
-func Error(v ...interface{}) {
-	globalLogger.logf(ERROR, fmt.Sprint(v...))
-}
+func (l *Logger) Error(v ...interface{}) { l.log(ERROR, fmt.Sprint(v...)) }
 
 // Errorf 格式化错误日志
-func Errorf(format string, v ...interface{}) {
-	globalLogger.logf(ERROR, format, v...)
-}
+func (l *Logger) Errorf(format string, v ...interface{}) { l.log(ERROR, fmt.Sprintf(format, v...)) }
+
+// Fatal 致命错误日志，输出后退出程序
+func (l *Logger) Fatal(v ...interface{}) { l.log(FATAL, fmt.Sprint(v...)) }
+
+// Fatalf 格式化致命错误日志，输出后退出程序
+func (l *Logger) Fatalf(format string, v ...interface{}) { l.log(FATAL, fmt.Sprintf(format, v...)) }
 
-// Fatal 致命错误日志
-func Fatal(v ...interface{}) {
-	globalLogger.logf(FATAL, fmt.Sprint(v...))
+// Recovered 记录recover捕获的panic及其堆栈，只有panic日志带堆栈
+func (l *Logger) Recovered(value interface{}) {
+	l.log(ERROR, fmt.Sprintf("panic: %v", value), "stack", string(debug.Stack()))
 }
 
-// Fatalf 格式化致命错误日志
-func Fatalf(format string, v ...interface{}) {
-	globalLogger.logf(FATAL, format, v...)
+// Debug 调试日志
+func Debug(v ...interface{}) { std.log(DEBUG, fmt.Sprint(v...)) }
+
+// Debugf 格式化调试日志
+func Debugf(format string, v ...interface{}) { std.log(DEBUG, fmt.Sprintf(format, v...)) }
+
+// Info 信息日志
+func Info(v ...interface{}) { std.log(INFO, fmt.Sprint(v...)) }
+
+// Infof 格式化信息日志
+func Infof(format string, v ...interface{}) { std.log(INFO, fmt.Sprintf(format, v...)) }
+
+// Warn 警告日志
+func Warn(v ...interface{}) { std.log(WARN, fmt.Sprint(v...)) }
+
+// Warnf 格式化警告日志
+func Warnf(format string, v ...interface{}) { std.log(WARN, fmt.Sprintf(format, v...)) }
+
+// Error 错误日志
+func Error(v ...interface{}) { std.log(ERROR, fmt.Sprint(v...)) }
+
+// Errorf 格式化错误日志
+func Errorf(format string, v ...interface{}) { std.log(ERROR, fmt.Sprintf(format, v...)) }
+
+// Fatal 致命错误日志，输出后退出程序
+func Fatal(v ...interface{}) { std.log(FATAL, fmt.Sprint(v...)) }
+
+// Fatalf 格式化致命错误日志，输出后退出程序
+func Fatalf(format string, v ...interface{}) { std.log(FATAL, fmt.Sprintf(format, v...)) }
+
+// Recovered 记录recover捕获的panic及其堆栈，只有panic日志带堆栈
+func Recovered(value interface{}) {
+	std.log(ERROR, fmt.Sprintf("panic: %v", value), "stack", string(debug.Stack()))
 }
@@ -0,0 +1,306 @@
+package hkvilog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 按时间轮转的周期
+const (
+	RotateHourly = "hourly"
+	RotateDaily  = "daily"
+)
+
+// backupTimeFormat 轮转文件名中的时间格式
+const backupTimeFormat = "20060102-150405"
+
+// RotateOptions 日志文件轮转配置
+type RotateOptions struct {
+	Filename   string // 日志文件路径
+	MaxSize    int    // 单个文件最大大小（MB），为0时不按大小轮转
+	Rotate     string // 按时间轮转：hourly、daily，为空时不按时间轮转
+	MaxBackups int    // 保留的轮转文件数，为0时全部保留
+	Compress   bool   // 使用gzip压缩轮转后的文件
+}
+
+// RotatingFile 按大小和时间自动轮转的日志文件，可以并发写入
+// 轮转后的文件命名为 <文件名>-<时间>.<扩展名>，如 gateway-20260101-000000.log
+type RotatingFile struct {
+	opts RotateOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	closed   bool // 已调用Close，不再写入
+	size     int64
+	openedAt time.Time // 当前文件所属周期内的时间，用于判断是否跨周期
+
+	millMu sync.Mutex // 串行化压缩和清理
+}
+
+// NewRotatingFile 创建轮转日志文件，目录不存在时自动创建
+func NewRotatingFile(opts RotateOptions) (*RotatingFile, error) {
+	switch opts.Rotate {
+	case "", RotateHourly, RotateDaily:
+	default:
+		return nil, fmt.Errorf("无效的日志轮转周期: %s", opts.Rotate)
+	}
+
+	f := &RotatingFile{opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write 写入日志，写入前按需轮转
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	// 上次轮转后未能打开任何文件时重新尝试打开
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			// 轮转失败但原文件已重新打开，继续写入原文件，下次写入时再尝试轮转
+			fmt.Fprintf(os.Stderr, "日志文件轮转失败: %v\n", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open 打开日志文件，已存在时追加写入
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.opts.Filename), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.opts.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	if f.size > 0 {
+		// 沿用已有文件时以其修改时间判断周期，避免上次运行的日志跨周期后仍写入同一文件
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// shouldRotate 检查写入n字节前是否需要轮转
+func (f *RotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSize > 0 && f.size+int64(n) > int64(f.opts.MaxSize)*1024*1024 {
+		return true
+	}
+	return f.period(f.openedAt) != f.period(time.Now())
+}
+
+// period 返回时间所属的轮转周期
+func (f *RotatingFile) period(t time.Time) string {
+	switch f.opts.Rotate {
+	case RotateHourly:
+		return t.Format("2006010215")
+	case RotateDaily:
+		return t.Format("20060102")
+	default:
+		return ""
+	}
+}
+
+// rotate 将当前文件改名为轮转文件并打开新文件，压缩和清理在后台进行
+// 改名或打开新文件失败时重新打开原文件继续写入，原文件也无法打开时f.file为nil
+func (f *RotatingFile) rotate() error {
+	backup := f.backupName(time.Now())
+
+	// Windows下无法重命名已打开的文件，需要先关闭
+	err := f.file.Close()
+	if err == nil {
+		err = os.Rename(f.opts.Filename, backup)
+	}
+	if err == nil {
+		err = f.open()
+	}
+	if err != nil {
+		if reopenErr := f.open(); reopenErr != nil {
+			f.file = nil
+			return fmt.Errorf("%v，重新打开日志文件失败: %v", err, reopenErr)
+		}
+		return err
+	}
+
+	go f.mill(backup)
+	return nil
+}
+
+// backupName 生成不与已有文件冲突的轮转文件名
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir := filepath.Dir(f.opts.Filename)
+	ext := filepath.Ext(f.opts.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(f.opts.Filename), ext)
+
+	name := filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext))
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = filepath.Join(dir, fmt.Sprintf("%s-%s.%d%s", prefix, t.Format(backupTimeFormat), i, ext))
+	}
+	return name
+}
+
+// mill 压缩轮转文件并删除超出保留数量的旧文件
+func (f *RotatingFile) mill(backup string) {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	if f.opts.Compress {
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "压缩日志文件 %s 失败: %v\n", backup, err)
+		}
+	}
+
+	if f.opts.MaxBackups <= 0 {
+		return
+	}
+
+	backups := f.backups()
+	for i := 0; i < len(backups)-f.opts.MaxBackups; i++ {
+		if err := os.Remove(backups[i]); err != nil {
+			fmt.Fprintf(os.Stderr, "删除日志文件 %s 失败: %v\n", backups[i], err)
+		}
+	}
+}
+
+// backups 返回按时间从旧到新排列的轮转文件
+func (f *RotatingFile) backups() []string {
+	dir := filepath.Dir(f.opts.Filename)
+	ext := filepath.Ext(f.opts.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(f.opts.Filename), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	type backup struct {
+		name    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !strings.HasSuffix(name, ext) && !strings.HasSuffix(name, ext+".gz") {
+			continue
+		}
+		// 时间部分必须符合格式，避免误删其他同前缀文件
+		stamp := strings.TrimPrefix(name, prefix)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, stamp[:len(backupTimeFormat)]); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{name: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+
+	// 同一秒内多次轮转的文件名带序号，按修改时间排序才能保证顺序正确
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.Before(backups[j].modTime)
+	})
+
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = b.name
+	}
+	return names
+}
+
+// compressFile 将文件压缩为.gz并删除原文件
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	src.Close()
+	return os.Remove(name)
+}
+
+// fileExists 检查文件是否存在
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -9,65 +11,100 @@ import (
 
 // Claims JWT声明结构体
 type Claims struct {
-	UserID    int    `json:"user_id"`    // 用户ID
-	Username  string `json:"username"`   // 用户名
-	TokenType string `json:"token_type"` // token类型：access 或 refresh
+	UserID      int      `json:"user_id"`               // 用户ID
+	Username    string   `json:"username"`              // 用户名
+	SessionID   string   `json:"sid"`                   // 会话ID
+	Roles       []string `json:"roles,omitempty"`       // 角色列表
+	Permissions []string `json:"permissions,omitempty"` // 权限列表
+	TokenType   string   `json:"token_type"`            // token类型：access 或 refresh
 	jwt.RegisteredClaims
 }
 
+// TokenSubject 令牌主体，即写入令牌的用户信息
+type TokenSubject struct {
+	UserID      int      // 用户ID
+	Username    string   // 用户名
+	SessionID   string   // 会话ID
+	Roles       []string // 角色列表
+	Permissions []string // 权限列表
+}
+
+// TokenPair 访问令牌和刷新令牌对
+type TokenPair struct {
+	AccessToken   string  // 访问令牌
+	RefreshToken  string  // 刷新令牌
+	AccessClaims  *Claims // 访问令牌声明
+	RefreshClaims *Claims // 刷新令牌声明
+}
+
 // GenerateTokenPair 生成访问令牌和刷新令牌对
-func GenerateTokenPair(userID int, username, accessSecretKey, refreshSecretKey string, accessExpire, refreshExpire int) (string, string, error) {
+func GenerateTokenPair(subject *TokenSubject, accessKeys, refreshKeys *KeySet, accessExpire, refreshExpire int) (*TokenPair, error) {
 	// 生成访问令牌
-	accessToken, err := GenerateToken(userID, username, "access", accessSecretKey, accessExpire)
+	accessToken, accessClaims, err := GenerateToken(subject, "access", accessKeys, accessExpire)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	// 生成刷新令牌
-	refreshToken, err := GenerateToken(userID, username, "refresh", refreshSecretKey, refreshExpire)
+	refreshToken, refreshClaims, err := GenerateToken(subject, "refresh", refreshKeys, refreshExpire)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return accessToken, refreshToken, nil
+	return &TokenPair{
+		AccessToken:   accessToken,
+		RefreshToken:  refreshToken,
+		AccessClaims:  accessClaims,
+		RefreshClaims: refreshClaims,
+	}, nil
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID int, username, tokenType, secretKey string, expireTime int) (string, error) {
+// GenerateToken 生成JWT token，同时返回令牌的声明
+func GenerateToken(subject *TokenSubject, tokenType string, keys *KeySet, expireTime int) (string, *Claims, error) {
+	// 生成令牌唯一标识（jti），用于退出登录时加入黑名单
+	tokenID, err := GenerateTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
 	// 创建声明
-	claims := Claims{
-		UserID:    userID,
-		Username:  username,
-		TokenType: tokenType,
+	claims := &Claims{
+		UserID:      subject.UserID,
+		Username:    subject.Username,
+		SessionID:   subject.SessionID,
+		Roles:       subject.Roles,
+		Permissions: subject.Permissions,
+		TokenType:   tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,                                                                     // 令牌唯一标识
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expireTime) * time.Second)), // 过期时间
 			IssuedAt:  jwt.NewNumericDate(time.Now()),                                              // 签发时间
 			NotBefore: jwt.NewNumericDate(time.Now()),                                              // 生效时间
 		},
 	}
 
-	// 创建token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// 签名token
-	tokenString, err := token.SignedString([]byte(secretKey))
+	// 使用当前签名密钥创建并签名token
+	tokenString, err := keys.Sign(claims)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return tokenString, nil
+	return tokenString, claims, nil
+}
+
+// GenerateTokenID 生成随机的令牌唯一标识
+func GenerateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // ParseToken 解析JWT token
-func ParseToken(tokenString, secretKey string) (*Claims, error) {
-	// 解析token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("无效的签名方法")
-		}
-		return []byte(secretKey), nil
-	})
+func ParseToken(tokenString string, keys *KeySet) (*Claims, error) {
+	// 解析token，由密钥集合根据kid选择验证密钥并校验签名方法；没有过期时间的token视为无效
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc, jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, err
@@ -82,8 +119,8 @@ func ParseToken(tokenString, secretKey string) (*Claims, error) {
 }
 
 // ValidateAccessToken 验证访问令牌
-func ValidateAccessToken(tokenString, secretKey string) (*Claims, error) {
-	claims, err := ParseToken(tokenString, secretKey)
+func ValidateAccessToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := ParseToken(tokenString, keys)
 	if err != nil {
 		return nil, err
 	}
@@ -96,8 +133,8 @@ func ValidateAccessToken(tokenString, secretKey string) (*Claims, error) {
 }
 
 // ValidateRefreshToken 验证刷新令牌
-func ValidateRefreshToken(tokenString, secretKey string) (*Claims, error) {
-	claims, err := ParseToken(tokenString, secretKey)
+func ValidateRefreshToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := ParseToken(tokenString, keys)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"gateway/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultAlgorithm 默认签名算法
+const DefaultAlgorithm = "HS256"
+
+// SigningKey 签名密钥
+type SigningKey struct {
+	ID         string            // 密钥ID（kid）
+	Method     jwt.SigningMethod // 签名算法
+	PrivateKey interface{}       // 签名密钥，仅用于验证的密钥为nil
+	PublicKey  interface{}       // 验证密钥，HMAC算法时与签名密钥相同
+}
+
+// KeySet 令牌密钥集合，包含一把当前签名密钥和若干验证密钥
+// 轮换密钥时保留旧密钥用于验证，已签发的令牌在过期前仍然有效
+type KeySet struct {
+	signing *SigningKey
+	keys    map[string]*SigningKey
+}
+
+// NewHMACKeySet 创建使用HS256共享密钥的密钥集合
+func NewHMACKeySet(secretKey string) *KeySet {
+	key := &SigningKey{
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: []byte(secretKey),
+		PublicKey:  []byte(secretKey),
+	}
+
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*SigningKey{"": key},
+	}
+}
+
+// LoadAccessKeySet 根据JWT配置加载访问令牌的密钥集合
+func LoadAccessKeySet(cfg *config.JWTConfig) (*KeySet, error) {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+
+	// HMAC算法继续使用共享密钥
+	if strings.HasPrefix(algorithm, "HS") {
+		if algorithm != DefaultAlgorithm {
+			return nil, fmt.Errorf("不支持的HMAC算法: %s", algorithm)
+		}
+		return NewHMACKeySet(cfg.AccessSecretKey), nil
+	}
+
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("签名算法 %s 需要配置密钥列表 jwt.keys", algorithm)
+	}
+
+	keySet := &KeySet{
+		keys: make(map[string]*SigningKey, len(cfg.Keys)),
+	}
+
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.ID == "" {
+			return nil, errors.New("JWT密钥缺少kid")
+		}
+		if _, exists := keySet.keys[keyCfg.ID]; exists {
+			return nil, fmt.Errorf("JWT密钥kid重复: %s", keyCfg.ID)
+		}
+
+		keyAlgorithm := keyCfg.Algorithm
+		if keyAlgorithm == "" {
+			keyAlgorithm = algorithm
+		}
+
+		key, err := loadSigningKey(keyCfg, keyAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("加载JWT密钥 %s 失败: %v", keyCfg.ID, err)
+		}
+		keySet.keys[key.ID] = key
+	}
+
+	signingKeyID := cfg.SigningKeyID
+	if signingKeyID == "" {
+		signingKeyID = cfg.Keys[0].ID
+	}
+
+	signing, ok := keySet.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("签名密钥 %s 不存在", signingKeyID)
+	}
+	if signing.PrivateKey == nil {
+		return nil, fmt.Errorf("签名密钥 %s 缺少私钥", signingKeyID)
+	}
+	keySet.signing = signing
+
+	return keySet, nil
+}
+
+// loadSigningKey 从PEM文件加载单把密钥
+func loadSigningKey(cfg config.JWTKeyConfig, algorithm string) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("不支持的签名算法: %s", algorithm)
+	}
+
+	key := &SigningKey{
+		ID:     cfg.ID,
+		Method: method,
+	}
+
+	var privatePEM, publicPEM []byte
+	var err error
+	if cfg.PrivateKeyFile != "" {
+		if privatePEM, err = os.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.PublicKeyFile != "" {
+		if publicPEM, err = os.ReadFile(cfg.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if privatePEM == nil && publicPEM == nil {
+		return nil, errors.New("至少需要配置私钥或公钥文件")
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if privatePEM != nil {
+			if key.PrivateKey, err = jwt.ParseRSAPrivateKeyFromPEM(privatePEM); err != nil {
+				return nil, err
+			}
+		}
+		if publicPEM != nil {
+			key.PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+		}
+	case *jwt.SigningMethodECDSA:
+		if privatePEM != nil {
+			if key.PrivateKey, err = jwt.ParseECPrivateKeyFromPEM(privatePEM); err != nil {
+				return nil, err
+			}
+		}
+		if publicPEM != nil {
+			key.PublicKey, err = jwt.ParseECPublicKeyFromPEM(publicPEM)
+		}
+	case *jwt.SigningMethodEd25519:
+		if privatePEM != nil {
+			if key.PrivateKey, err = jwt.ParseEdPrivateKeyFromPEM(privatePEM); err != nil {
+				return nil, err
+			}
+		}
+		if publicPEM != nil {
+			key.PublicKey, err = jwt.ParseEdPublicKeyFromPEM(publicPEM)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 未配置公钥时从私钥推导
+	if key.PublicKey == nil {
+		key.PublicKey = key.PrivateKey.(crypto.Signer).Public()
+	}
+
+	return key, nil
+}
+
+// Sign 使用当前签名密钥签名令牌
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+
+	return token.SignedString(ks.signing.PrivateKey)
+}
+
+// Keyfunc 根据令牌头中的kid选择验证密钥，并校验签名算法与密钥一致
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New("未知的签名密钥")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("无效的签名方法")
+	}
+
+	return key.PublicKey, nil
+}
+
+// JWK JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`           // 密钥类型
+	Kid string `json:"kid"`           // 密钥ID
+	Use string `json:"use"`           // 用途
+	Alg string `json:"alg"`           // 签名算法
+	N   string `json:"n,omitempty"`   // RSA模数
+	E   string `json:"e,omitempty"`   // RSA指数
+	Crv string `json:"crv,omitempty"` // 曲线名称
+	X   string `json:"x,omitempty"`   // 曲线X坐标或Ed25519公钥
+	Y   string `json:"y,omitempty"`   // 曲线Y坐标
+}
+
+// JWKS 返回所有非对称验证密钥的公钥集合，HMAC密钥不会公开
+func (ks *KeySet) JWKS() []JWK {
+	jwks := make([]JWK, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		jwk := JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	sort.Slice(jwks, func(i, j int) bool {
+		return jwks[i].Kid < jwks[j].Kid
+	})
+
+	return jwks
+}
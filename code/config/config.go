@@ -46,6 +46,9 @@ type RedisConfig struct {
 
 // SMSConfig 短信服务配置
 type SMSConfig struct {
+	Provider        string `json:"provider"`          // 短信服务提供方：console（默认，不真正发送）、aliyun、http_mock
+	FilePath        string `json:"file_path"`         // console：短信记录文件路径，为空时输出到日志
+	MockURL         string `json:"mock_url"`          // http_mock：接收短信的模拟服务地址
 	AccessKeyID     string `json:"access_key_id"`     // 阿里云AccessKey ID
 	AccessKeySecret string `json:"access_key_secret"` // 阿里云AccessKey Secret
 	SignName        string `json:"sign_name"`         // 短信签名
@@ -79,6 +82,7 @@ func LoadConfig() *Config {
 			DB:       0,
 		},
 		SMS: SMSConfig{
+			Provider:        "console",
 			AccessKeyID:     "your-access-key-id",
 			AccessKeySecret: "your-access-key-secret",
 			SignName:        "your-sign-name",
@@ -184,6 +188,15 @@ func loadFromEnv(config *Config) {
 	if regionID := os.Getenv("SMS_REGION_ID"); regionID != "" {
 		config.SMS.RegionID = regionID
 	}
+	if provider := os.Getenv("SMS_PROVIDER"); provider != "" {
+		config.SMS.Provider = provider
+	}
+	if filePath := os.Getenv("SMS_FILE_PATH"); filePath != "" {
+		config.SMS.FilePath = filePath
+	}
+	if mockURL := os.Getenv("SMS_MOCK_URL"); mockURL != "" {
+		config.SMS.MockURL = mockURL
+	}
 
 	// SMS配置
 	if accessKeyID := os.Getenv("SMS_ACCESS_KEY_ID"); accessKeyID != "" {
@@ -58,7 +58,7 @@ func (h *SMSHandler) SendSMS(c *gin.Context) {
 	}
 
 	// 发送短信验证码
-	code, err := h.smsService.SendSMSCode(req.Phone)
+	code, err := h.smsService.SendSMSCode(c.Request.Context(), req.Phone)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
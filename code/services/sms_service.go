@@ -1,79 +1,76 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"login/cache"
 	"login/config"
+	"login/sms"
 	"login/utils/hkvilog"
-	"math/rand"
+	"math/big"
 	"strings"
 	"time"
-
-	"github.com/aliyun/alibaba-cloud-sdk-go/services/dysmsapi"
 )
 
 // SMSService 短信服务
 type SMSService struct {
-	client *dysmsapi.Client
+	sender sms.Sender
 	config *config.SMSConfig
 }
 
-// NewSMSService 创建短信服务实例
+// NewSMSService 创建短信服务实例，按配置选择短信发送器
 func NewSMSService(cfg *config.SMSConfig) (*SMSService, error) {
-	client, err := dysmsapi.NewClientWithAccessKey(
-		cfg.RegionID,
-		cfg.AccessKeyID,
-		cfg.AccessKeySecret,
-	)
+	sender, err := sms.NewSender(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("创建阿里云短信客户端失败: %v", err)
+		return nil, err
 	}
 
 	return &SMSService{
-		client: client,
+		sender: sender,
 		config: cfg,
 	}, nil
 }
 
-// GenerateSMSCode 生成6位数字验证码
-func (s *SMSService) GenerateSMSCode() string {
-	rand.Seed(time.Now().UnixNano())
-	code := rand.Intn(900000) + 100000 // 生成100000-999999之间的数字
-	return fmt.Sprintf("%06d", code)
+// GenerateSMSCode 使用crypto/rand生成6位数字验证码
+func (s *SMSService) GenerateSMSCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
 // SendSMSCode 发送短信验证码
-func (s *SMSService) SendSMSCode(phone string) (string, error) {
-	// 生成验证码，暂时写死为201707
-	code := "201707"
-	//code := s.GenerateSMSCode()
-	//
-	// 创建短信请求
-	//request := dysmsapi.CreateSendSmsRequest()
-	//request.Scheme = "https"
-	//request.PhoneNumbers = phone
-	//request.SignName = s.config.SignName
-	//request.TemplateCode = s.config.TemplateCode
-	//request.TemplateParam = fmt.Sprintf(`{"code":"%s"}`, code)
-	//
-	//// 发送短信
-	//response, err := s.client.SendSms(request)
-	//if err != nil {
-	//	return "", fmt.Errorf("发送短信失败: %v", err)
-	//}
-	//
-	//// 检查发送结果
-	//if response.Code != "OK" {
-	//	return "", fmt.Errorf("短信发送失败: %s", response.Message)
-	//}
-
-	// 将验证码存储到Redis，5分钟过期
-	err := cache.SetSMSCode(phone, code, 5*time.Minute)
+func (s *SMSService) SendSMSCode(ctx context.Context, phone string) (string, error) {
+	code, err := s.GenerateSMSCode()
+	if err != nil {
+		hkvilog.Errorf("生成验证码失败: %v", err)
+		return "", fmt.Errorf("生成验证码失败")
+	}
+
+	// 先将验证码存储到Redis再发送，避免用户收到短信时验证码尚未保存，5分钟过期
+	err = cache.SetSMSCode(phone, code, 5*time.Minute)
 	if err != nil {
 		hkvilog.Errorf("存储验证码失败: %v", err)
 		return "", fmt.Errorf("存储验证码失败")
 	}
 
+	// 发送短信，失败时删除已保存的验证码
+	err = s.sender.Send(ctx, &sms.Message{
+		Phone:        phone,
+		SignName:     s.config.SignName,
+		TemplateCode: s.config.TemplateCode,
+		Params:       map[string]string{"code": code},
+	})
+	if err != nil {
+		hkvilog.Errorf("发送短信验证码失败: %v", err)
+		if err := cache.DeleteSMSCode(phone); err != nil {
+			hkvilog.Errorf("删除验证码失败: %v", err)
+		}
+		return "", fmt.Errorf("发送短信失败，请稍后再试")
+	}
+
 	hkvilog.Infof("短信验证码已发送到 %s", phone)
 	return code, nil
 }
@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"login/config"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/dysmsapi"
+)
+
+// AliyunSender 阿里云短信发送器
+type AliyunSender struct {
+	client *dysmsapi.Client
+}
+
+// NewAliyunSender 创建阿里云短信发送器
+func NewAliyunSender(cfg *config.SMSConfig) (*AliyunSender, error) {
+	client, err := dysmsapi.NewClientWithAccessKey(
+		cfg.RegionID,
+		cfg.AccessKeyID,
+		cfg.AccessKeySecret,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建阿里云短信客户端失败: %v", err)
+	}
+
+	return &AliyunSender{client: client}, nil
+}
+
+// Send 通过阿里云发送短信
+// 旧版SDK不支持context，只能在发送前检查请求是否已取消
+func (s *AliyunSender) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	params, err := json.Marshal(msg.Params)
+	if err != nil {
+		return err
+	}
+
+	// 创建短信请求
+	request := dysmsapi.CreateSendSmsRequest()
+	request.Scheme = "https"
+	request.PhoneNumbers = msg.Phone
+	request.SignName = msg.SignName
+	request.TemplateCode = msg.TemplateCode
+	request.TemplateParam = string(params)
+
+	// 发送短信
+	response, err := s.client.SendSms(request)
+	if err != nil {
+		return fmt.Errorf("发送短信失败: %v", err)
+	}
+
+	// 检查发送结果
+	if response.Code != "OK" {
+		return fmt.Errorf("短信发送失败: %s", response.Message)
+	}
+
+	return nil
+}
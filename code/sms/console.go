@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"login/utils/hkvilog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ConsoleSender 开发环境使用的短信发送器，不真正发送短信
+// 配置了文件路径时将短信以JSON行追加写入文件，否则输出到日志
+type ConsoleSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// consoleRecord 写入文件的短信记录
+type consoleRecord struct {
+	SentAt time.Time `json:"sent_at"` // 发送时间
+	*Message
+}
+
+// NewConsoleSender 创建开发环境短信发送器
+func NewConsoleSender(path string) *ConsoleSender {
+	return &ConsoleSender{path: path}
+}
+
+// Send 记录短信内容
+func (s *ConsoleSender) Send(ctx context.Context, msg *Message) error {
+	if s.path == "" {
+		hkvilog.Infof("[短信] 手机号: %s，签名: %s，模板: %s，参数: %v", msg.Phone, msg.SignName, msg.TemplateCode, msg.Params)
+		return nil
+	}
+
+	data, err := json.Marshal(consoleRecord{SentAt: time.Now(), Message: msg})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("打开短信记录文件失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入短信记录文件失败: %v", err)
+	}
+	return nil
+}
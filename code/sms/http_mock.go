@@ -0,0 +1,55 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpMockTimeout 请求模拟短信服务的超时时间
+const httpMockTimeout = 5 * time.Second
+
+// HTTPMockSender 将短信以JSON POST到模拟服务的发送器
+// 用于测试环境，测试可以在模拟服务中断言发送的短信，而不需要访问真实的短信服务
+type HTTPMockSender struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPMockSender 创建模拟短信发送器
+func NewHTTPMockSender(url string) *HTTPMockSender {
+	return &HTTPMockSender{
+		url:    url,
+		client: &http.Client{Timeout: httpMockTimeout},
+	}
+}
+
+// Send 将短信发送到模拟服务，非2xx响应视为发送失败
+func (s *HTTPMockSender) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送短信失败: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("短信发送失败: 模拟服务返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"login/config"
+)
+
+// Message 待发送的短信
+type Message struct {
+	Phone        string            `json:"phone"`         // 手机号
+	SignName     string            `json:"sign_name"`     // 短信签名
+	TemplateCode string            `json:"template_code"` // 短信模板代码
+	Params       map[string]string `json:"params"`        // 模板参数
+}
+
+// Sender 短信发送接口
+type Sender interface {
+	// Send 发送短信，返回错误表示发送失败；ctx结束时应尽快放弃发送
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewSender 根据配置创建短信发送器
+func NewSender(cfg *config.SMSConfig) (Sender, error) {
+	switch cfg.Provider {
+	case "aliyun":
+		return NewAliyunSender(cfg)
+	case "console":
+		return NewConsoleSender(cfg.FilePath), nil
+	case "http_mock":
+		if cfg.MockURL == "" {
+			return nil, fmt.Errorf("使用http_mock时必须配置sms.mock_url")
+		}
+		return NewHTTPMockSender(cfg.MockURL), nil
+	default:
+		return nil, fmt.Errorf("不支持的短信服务提供方: %s", cfg.Provider)
+	}
+}